@@ -1,7 +1,15 @@
+// The parsing itself lives in the rawimage package which is shared with
+// challenge3. The homework has a narrower interface and asks for the legacy
+// alpha blending so this is just a thin layer on top of it.
+
 package main
 
 import (
-	"fmt"
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/ironsmile/go-fmi/rawimage"
 )
 
 type Header struct {
@@ -9,42 +17,7 @@ type Header struct {
 	LineWidth uint
 }
 
-type Pixel struct {
-	Red              byte
-	Green            byte
-	Blue             byte
-	Alpha            byte
-	needsPremultiply bool
-}
-
-// In case *someone* calls us with a wrong method name
-// I will penalise him/her with one extra copy
-func (pixel *Pixel) Color() Pixel {
-	return pixel.Colour()
-}
-
-// quack quack!
-func (pixel *Pixel) Colour() Pixel {
-	return *pixel
-}
-
-func (pixel *Pixel) premultiply() {
-	if !pixel.needsPremultiply {
-		return
-	}
-
-	pixel.needsPremultiply = false
-
-	pixel.Red = alphaBlend(pixel.Red, pixel.Alpha)
-	pixel.Green = alphaBlend(pixel.Green, pixel.Alpha)
-	pixel.Blue = alphaBlend(pixel.Blue, pixel.Alpha)
-
-}
-
-func (pixel Pixel) String() string {
-	return fmt.Sprintf("Red: %d, Green: %d, Blue: %d", pixel.Red, pixel.Green,
-		pixel.Blue)
-}
+type Pixel = rawimage.Pixel
 
 type Image struct {
 	header Header
@@ -56,47 +29,87 @@ func (img *Image) InspectPixel(x uint, y uint) Pixel {
 	return img.data[index]
 }
 
+// The homework has always been lenient with its data. The pixels are one long
+// run which the line width only indexes into, so partial rows and a zero line
+// width are fine, and a trailing partial pixel is dropped. That is why the
+// data is parsed as a single row of whole pixels.
+//
+// It is just as lenient with the format, see parseChannels.
 func ParseImage(data []byte, header Header) *Image {
-	image := new(Image)
-	image.header = header
+	image := &Image{header: header}
 
-	var pixel *Pixel
-	format_len := len(header.Format)
+	pixelSize := len(header.Format)
+	pixels := len(data) / pixelSize
 
-	for index, colour_intensity := range data {
+	if pixels == 0 {
+		return image
+	}
 
-		format_index := index % format_len
-		if format_index == 0 {
-			pixel = new(Pixel)
+	data = data[:pixels*pixelSize]
+
+	// rawimage has formats of its own, such as "Y" and "RGBA16", which the
+	// homework never had. Only R, G, B and A mean the same to both.
+	if strings.Trim(header.Format, "RGBA") == "" {
+		row, err := parseRow(data, header.Format)
+
+		if err == nil {
+			image.data = row
+			return image
 		}
 
-		colour := header.Format[format_index]
-
-		switch (string)(colour) {
-		case "R":
-			pixel.Red = colour_intensity
-		case "G":
-			pixel.Green = colour_intensity
-		case "B":
-			pixel.Blue = colour_intensity
-		case "A":
-			pixel.Alpha = colour_intensity
-			pixel.needsPremultiply = true
+		if !errors.Is(err, rawimage.ErrBadFormat) {
+			panic(err)
 		}
+	}
+
+	image.data = parseChannels(data, header.Format)
 
-		if format_index == format_len-1 {
-			pixel.premultiply()
-			image.data = append(image.data, *pixel)
+	return image
+}
+
+// Formats rawimage does not take are parsed the way the homework always did
+// it. Every letter is a byte of the pixel and only R, G, B and A are channels,
+// the rest are skipped. The channels are gathered as RGBA for rawimage, which
+// is opaque when the format has no A, the same as "RGB" is.
+func parseChannels(data []byte, format string) []Pixel {
+	pixelSize := len(format)
+	rgba := make([]byte, len(data)/pixelSize*4)
+
+	for pixel := 0; pixel < len(data)/pixelSize; pixel++ {
+		rgba[pixel*4+3] = 255
+
+		for index, channel := range []byte(format) {
+			if offset := strings.IndexByte("RGBA", channel); offset >= 0 {
+				rgba[pixel*4+offset] = data[pixel*pixelSize+index]
+			}
 		}
+	}
 
+	row, err := parseRow(rgba, "RGBA")
+	if err != nil {
+		panic(err)
 	}
 
-	return image
+	return row
 }
 
-func alphaBlend(colour byte, alpha byte) byte {
-	// WRONG!
-	// should be (byte)(((int)(colour)*(int)(alpha) + 127) / 255)
-	// but due to explicit requirements it is as it is
-	return (byte)((((float64)(colour) / 255.0) * ((float64)(alpha) / 255.0)) * 255.0)
+func parseRow(data []byte, format string) ([]Pixel, error) {
+	header := rawimage.Header{
+		Format:    format,
+		LineWidth: (uint)(len(data) / len(format)),
+		Encoding:  "None",
+	}
+
+	options := rawimage.Options{
+		Blend:  rawimage.LegacyBlend,
+		Limits: rawimage.Limits{MaxWidth: -1},
+	}
+
+	decoder, err := rawimage.NewDecoderWithOptions(bytes.NewReader(data), header,
+		options)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.NextRow()
 }
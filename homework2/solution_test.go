@@ -245,3 +245,64 @@ func TestMaxAlphaWithRGBA(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestShortData(t *testing.T) {
+	data := []byte{
+		1, 2, 3, 4, 5, 6,
+		7, 8, 9, 10,
+	}
+
+	picture := ParseImage(data, Header{"RGB", 2})
+
+	if err := assertColor(picture.InspectPixel(1, 0), 4, 5, 6); err != nil {
+		t.Error(err)
+	}
+
+	if err := assertColor(picture.InspectPixel(0, 1), 7, 8, 9); err != nil {
+		t.Error(err)
+	}
+
+	picture = ParseImage(data, Header{"RGB", 0})
+
+	if err := assertColor(picture.InspectPixel(2, 5), 7, 8, 9); err != nil {
+		t.Error(err)
+	}
+
+	if picture = ParseImage(data[:2], Header{"RGB", 2}); len(picture.data) != 0 {
+		t.Errorf("Expected no pixels but found %d", len(picture.data))
+	}
+}
+
+func TestLenientFormats(t *testing.T) {
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	cases := []struct {
+		format   string
+		data     []byte
+		expected [][]byte
+	}{
+		{"RGBX", data, [][]byte{{1, 2, 3}, {5, 6, 7}}},
+		{"RRGB", data, [][]byte{{2, 3, 4}, {6, 7, 8}}},
+		{"rgb", data, [][]byte{{0, 0, 0}, {0, 0, 0}, {0, 0, 0}}},
+		{"BGRAA", data, [][]byte{{0, 0, 0}, {0, 0, 0}}},
+		{"XRGBA", []byte{1, 2, 3, 4, 255, 6, 7, 8, 9, 255},
+			[][]byte{{2, 3, 4}, {7, 8, 9}}},
+	}
+
+	for _, test := range cases {
+		picture := ParseImage(test.data, Header{test.format, 1})
+
+		if len(picture.data) != len(test.expected) {
+			t.Errorf("Format %s: expected %d pixels but got %d", test.format,
+				len(test.expected), len(picture.data))
+			continue
+		}
+
+		for y, colour := range test.expected {
+			if err := assertColor(picture.InspectPixel(0, (uint)(y)),
+				colour...); err != nil {
+				t.Errorf("Format %s, pixel %d: %s", test.format, y, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package rawimage
+
+// AlphaMode tells the parser what to do with the colour of pixels which have
+// an alpha channel.
+type AlphaMode int
+
+const (
+	// The colour is multiplied by alpha. This is how ParseImage has always
+	// worked and the only representation color.Color knows about.
+	PremultipliedAlpha AlphaMode = iota
+
+	// The colour is kept exactly as it was in the data. Fully transparent
+	// pixels do not lose their colour this way.
+	StraightAlpha
+
+	// The pixel fields are premultiplied as usual but the straight colour is
+	// kept as well and Straight returns it without any loss.
+	BothAlpha
+)
+
+// BlendMode chooses how colours are multiplied by alpha while parsing
+type BlendMode int
+
+const (
+	// Rounds to the nearest value. This is what challenge3 expects.
+	RoundedBlend BlendMode = iota
+
+	// Multiplies in floating point and truncates, which is off by one for
+	// about half of the colours. homework2 explicitly requires it so it is
+	// kept around for compatibility.
+	LegacyBlend
+)
+
+// Options change the way an image is parsed. The zero value gives the same
+// result as ParseImage.
+type Options struct {
+	Alpha  AlphaMode
+	Blend  BlendMode
+	Limits Limits
+}
+
+func (mode BlendMode) blend(colour byte, alpha byte) byte {
+	if mode == LegacyBlend {
+		return (byte)((((float64)(colour) / 255.0) * ((float64)(alpha) / 255.0)) * 255.0)
+	}
+	return alphaBlend(colour, alpha)
+}
+
+func (mode BlendMode) blend16(colour uint16, alpha uint16) uint16 {
+	if mode == LegacyBlend {
+		return (uint16)((((float64)(colour) / 65535.0) *
+			((float64)(alpha) / 65535.0)) * 65535.0)
+	}
+	return alphaBlend16(colour, alpha)
+}
+
+// Premultiplied returns the colour multiplied by alpha no matter how the pixel
+// was parsed.
+func (pixel *Pixel) Premultiplied() (red, green, blue, alpha byte) {
+	if !pixel.straight {
+		return pixel.Red, pixel.Green, pixel.Blue, pixel.Alpha
+	}
+
+	if pixel.deep {
+		r, g, b, a := pixel.RGBA64()
+		return (byte)(r >> 8), (byte)(g >> 8), (byte)(b >> 8), (byte)(a >> 8)
+	}
+
+	return alphaBlend(pixel.Red, pixel.Alpha), alphaBlend(pixel.Green, pixel.Alpha),
+		alphaBlend(pixel.Blue, pixel.Alpha), pixel.Alpha
+}
+
+// Straight returns the colour before it was multiplied by alpha. Unless the
+// pixel was parsed with StraightAlpha or BothAlpha it has to be calculated
+// back from the premultiplied colour, which is lossy for low alpha values and
+// gives black for transparent pixels.
+func (pixel *Pixel) Straight() (red, green, blue, alpha byte) {
+	if pixel.straight && !pixel.deep {
+		return pixel.Red, pixel.Green, pixel.Blue, pixel.Alpha
+	}
+
+	if pixel.straight || pixel.keepsStraight || pixel.deep {
+		r, g, b, a := pixel.Straight64()
+		return (byte)(r >> 8), (byte)(g >> 8), (byte)(b >> 8), (byte)(a >> 8)
+	}
+
+	return alphaUnblend(pixel.Red, pixel.Alpha),
+		alphaUnblend(pixel.Green, pixel.Alpha),
+		alphaUnblend(pixel.Blue, pixel.Alpha), pixel.Alpha
+}
+
+// Straight64 is Straight in full 16-bit precision
+func (pixel *Pixel) Straight64() (red, green, blue, alpha uint16) {
+	switch {
+	case pixel.straight && pixel.deep:
+		return pixel.wide.red, pixel.wide.green, pixel.wide.blue, pixel.wide.alpha
+	case pixel.straight:
+		return widen(pixel.Red), widen(pixel.Green), widen(pixel.Blue),
+			widen(pixel.Alpha)
+	case pixel.keepsStraight:
+		return pixel.straightWide.red, pixel.straightWide.green,
+			pixel.straightWide.blue, pixel.straightWide.alpha
+	}
+
+	r, g, b, a := pixel.RGBA64()
+
+	if !pixel.deep {
+		// Stay consistent with the 8-bit Straight
+		red, green, blue, _ := pixel.Straight()
+		return widen(red), widen(green), widen(blue), a
+	}
+
+	return alphaUnblend16(r, a), alphaUnblend16(g, a), alphaUnblend16(b, a), a
+}
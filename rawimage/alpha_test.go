@@ -0,0 +1,121 @@
+package rawimage
+
+import (
+	"testing"
+)
+
+func assertChannels(t *testing.T, what string, found [4]byte, expected ...byte) {
+	if found != [4]byte{expected[0], expected[1], expected[2], expected[3]} {
+		t.Errorf("Wrong %s colour: expected %v, got %v", what, expected, found)
+	}
+}
+
+func straightOf(pixel *Pixel) [4]byte {
+	r, g, b, a := pixel.Straight()
+	return [4]byte{r, g, b, a}
+}
+
+func TestParseWithStraightAlpha(t *testing.T) {
+	data := []byte{
+		22, 12, 244, 127, 200, 100, 50, 0,
+	}
+
+	picture, parseError := ParseImageWithOptions(data, newHeader("RGBA", 2, "None"),
+		Options{Alpha: StraightAlpha})
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	pixel, _ := picture.InspectPixel(0, 0)
+
+	if err := assertColor(pixel, 22, 12, 244); err != nil {
+		t.Error(err)
+	}
+
+	assertChannels(t, "premultiplied", premultipliedOf(pixel), 11, 6, 122, 127)
+
+	transparent, _ := picture.InspectPixel(1, 0)
+
+	assertChannels(t, "straight", straightOf(transparent), 200, 100, 50, 0)
+
+	if r, g, b, a := transparent.RGBA(); r|g|b|a != 0 {
+		t.Errorf("Transparent pixel was not transparent black: %d %d %d %d",
+			r, g, b, a)
+	}
+}
+
+func TestParseWithBothAlpha(t *testing.T) {
+	data := []byte{
+		22, 12, 244, 127, 200, 100, 50, 0,
+	}
+
+	picture, _ := ParseImageWithOptions(data, newHeader("RGBA", 2, "None"),
+		Options{Alpha: BothAlpha})
+
+	pixel, _ := picture.InspectPixel(0, 0)
+
+	if err := assertColor(pixel, 11, 6, 122); err != nil {
+		t.Error(err)
+	}
+
+	assertChannels(t, "straight", straightOf(pixel), 22, 12, 244, 127)
+
+	transparent, _ := picture.InspectPixel(1, 0)
+
+	assertChannels(t, "straight", straightOf(transparent), 200, 100, 50, 0)
+	assertChannels(t, "premultiplied", premultipliedOf(transparent), 0, 0, 0, 0)
+}
+
+func TestStraightAlphaIsApproximatedForPremultipliedPixels(t *testing.T) {
+	picture, _ := ParseImage([]byte{22, 12, 244, 127, 1, 2, 3, 0},
+		newHeader("RGBA", 2, "None"))
+
+	assertChannels(t, "straight", straightOf(&picture.data[0]), 22, 12, 245, 127)
+	assertChannels(t, "straight", straightOf(&picture.data[1]), 0, 0, 0, 0)
+}
+
+func TestStraightAlphaWith16BitFormats(t *testing.T) {
+	data := []byte{
+		0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0x00, 0x00,
+	}
+
+	header := newHeader("RGBA16", 1, "None")
+
+	for _, mode := range []AlphaMode{StraightAlpha, BothAlpha} {
+		picture, _ := ParseImageWithOptions(data, header, Options{Alpha: mode})
+
+		r, g, b, a := picture.data[0].Straight64()
+
+		if r != 0x1234 || g != 0x5678 || b != 0x9abc || a != 0 {
+			t.Errorf("Mode %d: wrong straight colour %x %x %x %x", mode, r, g, b, a)
+		}
+	}
+}
+
+func TestEncodeKeepsStraightColour(t *testing.T) {
+	data := []byte{
+		22, 12, 244, 1, 200, 100, 50, 0,
+	}
+
+	header := newHeader("RGBA", 2, "None")
+
+	for _, mode := range []AlphaMode{StraightAlpha, BothAlpha} {
+		picture, _ := ParseImageWithOptions(data, header, Options{Alpha: mode})
+
+		encoded, err := Encode(picture, newHeader("ARGB", 2, "None"))
+
+		if err != nil {
+			t.Fatalf("Encoding returned error: %s", err)
+		}
+
+		expected := []byte{1, 22, 12, 244, 0, 200, 100, 50}
+
+		for index := range expected {
+			if encoded[index] != expected[index] {
+				t.Errorf("Mode %d: expected %v but got %v", mode, expected, encoded)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package rawimage
+
+import (
+	"image/color"
+	"math"
+)
+
+// Colour space conversions work on the straight colour, the premultiplied one
+// means nothing to them. Alpha is left out and has to be given back when
+// converting to a Pixel again.
+
+// HSV with hue in degrees from 0 to 360 and saturation and value from 0 to 1
+type HSV struct {
+	H, S, V float64
+}
+
+// HSL with hue in degrees from 0 to 360 and saturation and lightness from 0
+// to 1
+type HSL struct {
+	H, S, L float64
+}
+
+// CIE L*a*b* with a D65 white point. L goes from 0 to 100 while a and b are
+// roughly between -128 and 127.
+type Lab struct {
+	L, A, B float64
+}
+
+// HSV converts the straight colour of the pixel to hue, saturation and value
+func (pixel *Pixel) HSV() HSV {
+	red, green, blue := pixel.unitStraight()
+	maximum, minimum, hue := hueOf(red, green, blue)
+
+	hsv := HSV{H: hue, V: maximum}
+	if maximum > 0 {
+		hsv.S = (maximum - minimum) / maximum
+	}
+
+	return hsv
+}
+
+// HSL converts the straight colour of the pixel to hue, saturation and
+// lightness
+func (pixel *Pixel) HSL() HSL {
+	red, green, blue := pixel.unitStraight()
+	maximum, minimum, hue := hueOf(red, green, blue)
+
+	hsl := HSL{H: hue, L: (maximum + minimum) / 2}
+	if chroma := maximum - minimum; chroma > 0 {
+		hsl.S = chroma / (1 - math.Abs(2*hsl.L-1))
+	}
+
+	return hsl
+}
+
+// YCbCr uses the same JPEG conversion as the image/color package
+func (pixel *Pixel) YCbCr() color.YCbCr {
+	red, green, blue, _ := pixel.Straight()
+	y, cb, cr := color.RGBToYCbCr(red, green, blue)
+	return color.YCbCr{Y: y, Cb: cb, Cr: cr}
+}
+
+// Lab converts the pixel to CIE L*a*b*, taking its colour to be sRGB
+func (pixel *Pixel) Lab() Lab {
+	red, green, blue := pixel.unitStraight()
+	red, green, blue = linearise(red), linearise(green), linearise(blue)
+
+	x := (0.4124564*red + 0.3575761*green + 0.1804375*blue) / whiteX
+	y := (0.2126729*red + 0.7151522*green + 0.0721750*blue) / whiteY
+	z := (0.0193339*red + 0.1191920*green + 0.9503041*blue) / whiteZ
+
+	x, y, z = labCompand(x), labCompand(y), labCompand(z)
+
+	return Lab{L: 116*y - 16, A: 500 * (x - y), B: 200 * (y - z)}
+}
+
+// Pixel returns the colour premultiplied with alpha
+func (hsv HSV) Pixel(alpha byte) Pixel {
+	chroma := hsv.V * hsv.S
+	red, green, blue := fromHue(hsv.H, chroma)
+	offset := hsv.V - chroma
+	return pixelFromUnit(red+offset, green+offset, blue+offset, alpha)
+}
+
+// Pixel returns the colour premultiplied with alpha
+func (hsl HSL) Pixel(alpha byte) Pixel {
+	chroma := (1 - math.Abs(2*hsl.L-1)) * hsl.S
+	red, green, blue := fromHue(hsl.H, chroma)
+	offset := hsl.L - chroma/2
+	return pixelFromUnit(red+offset, green+offset, blue+offset, alpha)
+}
+
+// PixelFromYCbCr is the opposite of Pixel.YCbCr
+func PixelFromYCbCr(ycbcr color.YCbCr, alpha byte) Pixel {
+	red, green, blue := color.YCbCrToRGB(ycbcr.Y, ycbcr.Cb, ycbcr.Cr)
+	return pixelFromUnit((float64)(red)/255, (float64)(green)/255,
+		(float64)(blue)/255, alpha)
+}
+
+// Pixel returns the colour premultiplied with alpha. Colours outside of sRGB
+// are clamped.
+func (lab Lab) Pixel(alpha byte) Pixel {
+	y := (lab.L + 16) / 116
+	x := y + lab.A/500
+	z := y - lab.B/200
+
+	x, y, z = labExpand(x)*whiteX, labExpand(y)*whiteY, labExpand(z)*whiteZ
+
+	red := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	green := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	blue := 0.0556434*x - 0.2040259*y + 1.0572252*z
+
+	return pixelFromUnit(compress(red), compress(green), compress(blue), alpha)
+}
+
+// Adjustments change the image in place. They go through the colour spaces
+// above, so they are only as precise as a float64 round trip is.
+
+// AdjustBrightness moves the L*a*b* lightness of every pixel by amount, which
+// goes from -1 for black to 1 for white.
+func (img *Image) AdjustBrightness(amount float64) {
+	img.adjust(func(pixel *Pixel) Pixel {
+		lab := pixel.Lab()
+		lab.L = math.Max(0, math.Min(100, lab.L+amount*100))
+		return lab.Pixel(pixel.Alpha)
+	})
+}
+
+// AdjustSaturation multiplies the HSL saturation of every pixel by factor. Zero
+// makes the image gray.
+func (img *Image) AdjustSaturation(factor float64) {
+	img.adjust(func(pixel *Pixel) Pixel {
+		hsl := pixel.HSL()
+		hsl.S = math.Max(0, math.Min(1, hsl.S*factor))
+		return hsl.Pixel(pixel.Alpha)
+	})
+}
+
+// RotateHue turns the hue of every pixel by degrees
+func (img *Image) RotateHue(degrees float64) {
+	img.adjust(func(pixel *Pixel) Pixel {
+		hsv := pixel.HSV()
+		hsv.H = math.Mod(math.Mod(hsv.H+degrees, 360)+360, 360)
+		return hsv.Pixel(pixel.Alpha)
+	})
+}
+
+func (img *Image) adjust(change func(pixel *Pixel) Pixel) {
+	for y := 0; y < img.height(); y++ {
+		row := img.row(y)
+		for x := range row {
+			row[x] = change(&row[x])
+		}
+	}
+}
+
+// D65 reference white
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+func (pixel *Pixel) unitStraight() (red, green, blue float64) {
+	r, g, b, _ := pixel.Straight64()
+	return (float64)(r) / 0xffff, (float64)(g) / 0xffff, (float64)(b) / 0xffff
+}
+
+// Premultiplies a straight colour with channels from 0 to 1
+func pixelFromUnit(red, green, blue float64, alpha byte) Pixel {
+	wideAlpha := widen(alpha)
+	channel := func(value float64) uint16 {
+		return alphaBlend16(clampChannel(value*0xffff, 0xffff), wideAlpha)
+	}
+	return newPixel16(channel(red), channel(green), channel(blue), wideAlpha)
+}
+
+func hueOf(red, green, blue float64) (maximum, minimum, hue float64) {
+	maximum = math.Max(red, math.Max(green, blue))
+	minimum = math.Min(red, math.Min(green, blue))
+	chroma := maximum - minimum
+
+	switch {
+	case chroma == 0:
+		hue = 0
+	case maximum == red:
+		hue = math.Mod((green-blue)/chroma+6, 6)
+	case maximum == green:
+		hue = (blue-red)/chroma + 2
+	default:
+		hue = (red-green)/chroma + 4
+	}
+
+	return maximum, minimum, hue * 60
+}
+
+// The colour with this hue and chroma and the smallest channel at zero
+func fromHue(hue, chroma float64) (red, green, blue float64) {
+	sector := math.Mod(math.Mod(hue, 360)+360, 360) / 60
+	second := chroma * (1 - math.Abs(math.Mod(sector, 2)-1))
+
+	switch (int)(sector) {
+	case 0:
+		return chroma, second, 0
+	case 1:
+		return second, chroma, 0
+	case 2:
+		return 0, chroma, second
+	case 3:
+		return 0, second, chroma
+	case 4:
+		return second, 0, chroma
+	}
+	return chroma, 0, second
+}
+
+// sRGB gamma to linear light
+func linearise(value float64) float64 {
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+// Linear light back to sRGB gamma
+func compress(value float64) float64 {
+	if value <= 0.0031308 {
+		return value * 12.92
+	}
+	return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
+
+func labCompand(value float64) float64 {
+	if value > 216.0/24389.0 {
+		return math.Cbrt(value)
+	}
+	return (24389.0/27.0*value + 16) / 116
+}
+
+func labExpand(value float64) float64 {
+	if cube := value * value * value; cube > 216.0/24389.0 {
+		return cube
+	}
+	return (116*value - 16) / (24389.0 / 27.0)
+}
@@ -0,0 +1,114 @@
+package rawimage
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func closeEnough(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestColourSpacesOfKnownColours(t *testing.T) {
+	red := rgba(255, 0, 0, 255)
+
+	if hsv := red.HSV(); hsv != (HSV{0, 1, 1}) {
+		t.Errorf("Wrong HSV for red: %v", hsv)
+	}
+
+	if hsl := red.HSL(); hsl != (HSL{0, 1, 0.5}) {
+		t.Errorf("Wrong HSL for red: %v", hsl)
+	}
+
+	if ycbcr := red.YCbCr(); ycbcr != (color.YCbCr{76, 85, 255}) {
+		t.Errorf("Wrong YCbCr for red: %v", ycbcr)
+	}
+
+	lab := red.Lab()
+
+	if !closeEnough(lab.L, 53.24, 0.01) || !closeEnough(lab.A, 80.09, 0.01) ||
+		!closeEnough(lab.B, 67.20, 0.01) {
+		t.Errorf("Wrong Lab for red: %v", lab)
+	}
+
+	whitePixel := rgba(255, 255, 255, 255)
+	white := whitePixel.Lab()
+
+	if !closeEnough(white.L, 100, 0.001) || !closeEnough(white.A, 0, 0.001) ||
+		!closeEnough(white.B, 0, 0.001) {
+		t.Errorf("Wrong Lab for white: %v", white)
+	}
+
+	teal := rgba(0, 128, 128, 255)
+
+	if hsv := teal.HSV(); !closeEnough(hsv.H, 180, 0.001) ||
+		!closeEnough(hsv.V, 128.0/255, 0.001) {
+		t.Errorf("Wrong HSV for teal: %v", hsv)
+	}
+}
+
+func TestColourSpacesRoundTrip(t *testing.T) {
+	for _, pixel := range []Pixel{
+		rgba(255, 0, 0, 255),
+		rgba(12, 200, 99, 255),
+		rgba(1, 2, 3, 255),
+		rgba(250, 250, 250, 255),
+		rgba(60, 30, 10, 128),
+	} {
+		conversions := map[string]Pixel{
+			"HSV":   pixel.HSV().Pixel(pixel.Alpha),
+			"HSL":   pixel.HSL().Pixel(pixel.Alpha),
+			"Lab":   pixel.Lab().Pixel(pixel.Alpha),
+			"YCbCr": PixelFromYCbCr(pixel.YCbCr(), pixel.Alpha),
+		}
+
+		for name, found := range conversions {
+			expected := premultipliedOf(&pixel)
+			tolerance := 0
+			if name == "YCbCr" {
+				// The conversion has to fit in bytes so it is lossy
+				tolerance = 2
+			}
+
+			got := premultipliedOf(&found)
+
+			for channel := range expected {
+				difference := (int)(expected[channel]) - (int)(got[channel])
+				if difference < -tolerance || difference > tolerance {
+					t.Errorf("%s round trip of %s gave %v", name, pixel, got)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestImageAdjustments(t *testing.T) {
+	data := []byte{
+		255, 0, 0, 255, 0, 0, 255, 128,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+	picture.RotateHue(120)
+
+	assertChannels(t, "hue rotated", straightOf(&picture.data[0]), 0, 255, 0, 255)
+	assertChannels(t, "hue rotated", straightOf(&picture.data[1]), 255, 0, 0, 128)
+
+	picture.AdjustSaturation(0)
+
+	for index := range picture.data {
+		red, green, blue, _ := picture.data[index].Straight()
+		if red != green || green != blue {
+			t.Errorf("Pixel %d was not gray: %s", index, picture.data[index])
+		}
+	}
+
+	picture.AdjustBrightness(1)
+
+	assertChannels(t, "brightened", straightOf(&picture.data[0]), 255, 255, 255, 255)
+
+	picture.AdjustBrightness(-1)
+
+	assertChannels(t, "darkened", straightOf(&picture.data[0]), 0, 0, 0, 255)
+}
@@ -0,0 +1,196 @@
+package rawimage
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// Comparison describes how two images of the same size differ. Pixels are
+// compared by their 8-bit premultiplied channels.
+type Comparison struct {
+	// Pixels with at least one different channel
+	Mismatched int
+
+	// The first of them in row order, or (-1, -1) if there are none
+	FirstMismatch image.Point
+
+	// The biggest difference of a single channel
+	MaxDelta byte
+
+	// Peak signal-to-noise ratio in decibels over all four channels. It is
+	// +Inf for identical images.
+	PSNR float64
+
+	// Structural similarity of the luminance, from -1 to 1 which means the
+	// images look the same.
+	SSIM float64
+
+	// An image as big as the compared ones. Pixels which match are a faded
+	// gray version of a, the others are red, brighter for bigger differences.
+	Diff *Image
+}
+
+// The side of the square windows over which SSIM is calculated. Neighbouring
+// windows overlap by half.
+const ssimWindow = 8
+
+// Compare finds the differences between a and b, which must be of the same
+// size.
+func Compare(a, b *Image) (*Comparison, error) {
+	width, height := a.width(), a.height()
+
+	if width != b.width() || height != b.height() {
+		return nil, newImageError(BadDimensions,
+			"Only images of the same size can be compared")
+	}
+
+	comparison := &Comparison{FirstMismatch: image.Pt(-1, -1)}
+	comparison.Diff = a.withWidth(width)
+	comparison.Diff.data = make([]Pixel, 0, width*height)
+
+	squares := 0.0
+
+	for y := 0; y < height; y++ {
+		rowA, rowB := a.row(y), b.row(y)
+		for x := range rowA {
+			first, second := premultipliedOf(&rowA[x]), premultipliedOf(&rowB[x])
+			delta := byte(0)
+
+			for channel := range first {
+				difference := (int)(first[channel]) - (int)(second[channel])
+				squares += (float64)(difference * difference)
+				delta = max(delta, (byte)(max(difference, -difference)))
+			}
+
+			comparison.MaxDelta = max(comparison.MaxDelta, delta)
+			comparison.Diff.data = append(comparison.Diff.data,
+				diffPixel(first, delta))
+
+			if delta == 0 {
+				continue
+			}
+
+			if comparison.Mismatched == 0 {
+				comparison.FirstMismatch = image.Pt(x, y)
+			}
+			comparison.Mismatched++
+		}
+	}
+
+	comparison.PSNR = math.Inf(1)
+	if squares > 0 {
+		meanSquare := squares / (float64)(width*height*4)
+		comparison.PSNR = 10 * math.Log10(255*255/meanSquare)
+	}
+
+	comparison.SSIM = ssim(a, b)
+
+	return comparison, nil
+}
+
+// EqualWithin reports whether a and b are of the same size and no channel of
+// theirs differs by more than tolerance. The comparison, or the error when
+// the sizes differ, explains why they are not.
+func EqualWithin(a, b *Image, tolerance byte) (bool, *Comparison, error) {
+	comparison, err := Compare(a, b)
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	return comparison.MaxDelta <= tolerance, comparison, nil
+}
+
+func (comparison *Comparison) String() string {
+	if comparison.Mismatched == 0 {
+		return "The images are identical"
+	}
+
+	return fmt.Sprintf("%d pixels differ, the first at %v, by up to %d with "+
+		"PSNR %.2f dB and SSIM %.4f", comparison.Mismatched,
+		comparison.FirstMismatch, comparison.MaxDelta, comparison.PSNR,
+		comparison.SSIM)
+}
+
+func diffPixel(colour [4]byte, delta byte) Pixel {
+	if delta > 0 {
+		return Pixel{Red: 128 + delta/2, Alpha: 255}
+	}
+
+	value := (byte)(luminance((uint32)(colour[0]), (uint32)(colour[1]),
+		(uint32)(colour[2])) / 4)
+	return Pixel{Red: value, Green: value, Blue: value, Alpha: 255}
+}
+
+// Mean SSIM of the luminance over overlapping windows. Images smaller than a
+// window are taken as a single one.
+func ssim(a, b *Image) float64 {
+	width, height := a.width(), a.height()
+
+	if width == 0 || height == 0 {
+		return 1
+	}
+
+	lumaA, lumaB := lumaPlane(a), lumaPlane(b)
+	windowWidth, windowHeight := min(ssimWindow, width), min(ssimWindow, height)
+	stepX, stepY := max(1, windowWidth/2), max(1, windowHeight/2)
+
+	total, windows := 0.0, 0
+
+	for top := 0; top+windowHeight <= height; top += stepY {
+		for left := 0; left+windowWidth <= width; left += stepX {
+			total += windowSSIM(lumaA, lumaB, width, left, top, windowWidth,
+				windowHeight)
+			windows++
+		}
+	}
+
+	return total / (float64)(windows)
+}
+
+func windowSSIM(lumaA, lumaB []float64, stride, left, top, width,
+	height int) float64 {
+
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+
+	var sumA, sumB, squaresA, squaresB, products float64
+
+	for y := top; y < top+height; y++ {
+		for x := left; x < left+width; x++ {
+			first, second := lumaA[y*stride+x], lumaB[y*stride+x]
+			sumA += first
+			sumB += second
+			squaresA += first * first
+			squaresB += second * second
+			products += first * second
+		}
+	}
+
+	count := (float64)(width * height)
+	meanA, meanB := sumA/count, sumB/count
+	varianceA := squaresA/count - meanA*meanA
+	varianceB := squaresB/count - meanB*meanB
+	covariance := products/count - meanA*meanB
+
+	return ((2*meanA*meanB + c1) * (2*covariance + c2)) /
+		((meanA*meanA + meanB*meanB + c1) * (varianceA + varianceB + c2))
+}
+
+func lumaPlane(img *Image) []float64 {
+	plane := make([]float64, 0, img.width()*img.height())
+
+	for y := 0; y < img.height(); y++ {
+		row := img.row(y)
+		for x := range row {
+			red, green, blue, _ := row[x].Premultiplied()
+			plane = append(plane, 0.299*(float64)(red)+0.587*(float64)(green)+
+				0.114*(float64)(blue))
+		}
+	}
+
+	return plane
+}
@@ -0,0 +1,85 @@
+package rawimage
+
+import (
+	"errors"
+	"image"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCompareIdenticalImages(t *testing.T) {
+	comparison, err := Compare(numberedImage(t), numberedImage(t))
+
+	if err != nil {
+		t.Fatalf("Comparing returned error: %s", err)
+	}
+
+	if comparison.Mismatched != 0 || comparison.MaxDelta != 0 ||
+		!math.IsInf(comparison.PSNR, 1) || math.Abs(comparison.SSIM-1) > 1e-9 {
+		t.Errorf("Identical images differ: %+v", comparison)
+	}
+
+	if comparison.FirstMismatch != image.Pt(-1, -1) {
+		t.Errorf("Expected no first mismatch but got %v",
+			comparison.FirstMismatch)
+	}
+}
+
+func TestCompareDifferentImages(t *testing.T) {
+	picture := numberedImage(t)
+	changed := numberedImage(t)
+	changed.SetPixel(1, 1, gray(15))
+
+	comparison, _ := Compare(picture, changed)
+
+	if comparison.Mismatched != 1 || comparison.FirstMismatch != image.Pt(1, 1) {
+		t.Errorf("Wrong mismatches: %d, first at %v", comparison.Mismatched,
+			comparison.FirstMismatch)
+	}
+
+	if comparison.MaxDelta != 10 {
+		t.Errorf("Expected max delta 10 but got %d", comparison.MaxDelta)
+	}
+
+	// Three channels off by 10 out of 24
+	expectedPSNR := 10 * math.Log10(255*255/(300.0/24))
+
+	if math.Abs(comparison.PSNR-expectedPSNR) > 1e-9 {
+		t.Errorf("Expected PSNR %f but got %f", expectedPSNR, comparison.PSNR)
+	}
+
+	if comparison.SSIM >= 1 {
+		t.Errorf("Different images have SSIM %f", comparison.SSIM)
+	}
+
+	diff := comparison.Diff.data
+
+	if diff[4].Red != 133 || diff[4].Green != 0 || diff[0].Red != diff[0].Green {
+		t.Errorf("Wrong diff image: %s and %s", diff[4], diff[0])
+	}
+
+	if !strings.HasPrefix(comparison.String(), "1 pixels differ, the first at (1,1)") {
+		t.Errorf("Unexpected description %q", comparison)
+	}
+}
+
+func TestEqualWithin(t *testing.T) {
+	picture := numberedImage(t)
+	changed := numberedImage(t)
+	changed.SetPixel(0, 0, gray(3))
+
+	if equal, _, _ := EqualWithin(picture, changed, 2); !equal {
+		t.Errorf("Images within tolerance were not equal")
+	}
+
+	if equal, comparison, _ := EqualWithin(picture, changed, 1); equal {
+		t.Errorf("Images out of tolerance were equal: %s", comparison)
+	}
+
+	_, _, err := EqualWithin(picture, picture.Rotate90(), 255)
+
+	if !errors.Is(err, ErrBadDimensions) {
+		t.Errorf("Expected bad dimensions error but got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package rawimage
+
+import (
+	"image"
+)
+
+// CompositeOp is the way a source pixel is combined with a destination one
+type CompositeOp int
+
+const (
+	// Porter-Duff operators. Source over destination and so on.
+	Over CompositeOp = iota
+	In
+	Out
+	Atop
+	Xor
+
+	// Separable blend modes. Both pixels are composited with Over but where
+	// they overlap the colour is the result of the blend.
+	Multiply
+	Screen
+	Overlay
+)
+
+// Composite places pixel on top of backdrop using op and returns the result.
+// The maths is done on the premultiplied colour in 16-bit precision.
+func (pixel Pixel) Composite(backdrop Pixel, op CompositeOp) Pixel {
+	source, destination := unitChannels(&pixel), unitChannels(&backdrop)
+	sourceAlpha, destinationAlpha := source[3], destination[3]
+
+	var result [4]float64
+
+	switch op {
+	case Over:
+		result = mix(source, 1, destination, 1-sourceAlpha)
+	case In:
+		result = mix(source, destinationAlpha, destination, 0)
+	case Out:
+		result = mix(source, 1-destinationAlpha, destination, 0)
+	case Atop:
+		result = mix(source, destinationAlpha, destination, 1-sourceAlpha)
+	case Xor:
+		result = mix(source, 1-destinationAlpha, destination, 1-sourceAlpha)
+	default:
+		result = mix(source, 1-destinationAlpha, destination, 1-sourceAlpha)
+		for channel := 0; channel < 3; channel++ {
+			result[channel] += op.blend(source[channel], sourceAlpha,
+				destination[channel], destinationAlpha)
+		}
+		result[3] = sourceAlpha + destinationAlpha - sourceAlpha*destinationAlpha
+	}
+
+	for channel := range result {
+		result[channel] *= 0xffff
+	}
+
+	return pixelFromSums(result)
+}
+
+// The blended part of a premultiplied colour, that is the blend function
+// multiplied by both alphas.
+func (op CompositeOp) blend(source, sourceAlpha, destination,
+	destinationAlpha float64) float64 {
+
+	switch op {
+	case Multiply:
+		return source * destination
+	case Screen:
+		return source*destinationAlpha + destination*sourceAlpha -
+			source*destination
+	case Overlay:
+		if sourceAlpha == 0 || destinationAlpha == 0 {
+			return 0
+		}
+
+		straightSource := source / sourceAlpha
+		straightDestination := destination / destinationAlpha
+		blended := 2 * straightSource * straightDestination
+
+		if straightDestination > 0.5 {
+			blended = 1 - 2*(1-straightSource)*(1-straightDestination)
+		}
+
+		return blended * sourceAlpha * destinationAlpha
+	}
+
+	return 0
+}
+
+// Composite places src on top of img with its top left corner at offset. Only
+// img changes and the parts of src outside of it are ignored.
+//
+// Everywhere outside of src the source is taken to be transparent, so In and
+// Out clear whatever of img is not covered by src just like Porter-Duff says.
+func (img *Image) Composite(src *Image, offset image.Point, op CompositeOp) {
+	sourceBounds := src.Bounds().Add(offset)
+
+	for y := 0; y < img.height(); y++ {
+		row := img.row(y)
+		for x := range row {
+			var source Pixel
+
+			if (image.Point{x, y}).In(sourceBounds) {
+				source = src.data[src.pixelIndex(x-offset.X, y-offset.Y)]
+			}
+
+			row[x] = source.Composite(row[x], op)
+		}
+	}
+}
+
+// The premultiplied channels in the range from 0 to 1
+func unitChannels(pixel *Pixel) [4]float64 {
+	red, green, blue, alpha := pixel.RGBA64()
+	return [4]float64{
+		(float64)(red) / 0xffff,
+		(float64)(green) / 0xffff,
+		(float64)(blue) / 0xffff,
+		(float64)(alpha) / 0xffff,
+	}
+}
+
+func mix(source [4]float64, sourceWeight float64, destination [4]float64,
+	destinationWeight float64) (result [4]float64) {
+
+	for channel := range result {
+		result[channel] = source[channel]*sourceWeight +
+			destination[channel]*destinationWeight
+	}
+	return
+}
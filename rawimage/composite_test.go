@@ -0,0 +1,96 @@
+package rawimage
+
+import (
+	"image"
+	"testing"
+)
+
+func rgba(red, green, blue, alpha byte) Pixel {
+	return Pixel{Red: red, Green: green, Blue: blue, Alpha: alpha}
+}
+
+func TestPorterDuffOperators(t *testing.T) {
+	// Premultiplied half transparent red and opaque blue
+	red := rgba(128, 0, 0, 128)
+	blue := rgba(0, 0, 255, 255)
+	transparent := rgba(0, 0, 0, 0)
+
+	cases := []struct {
+		source, destination Pixel
+		op                  CompositeOp
+		expected            Pixel
+	}{
+		{red, blue, Over, rgba(128, 0, 127, 255)},
+		{blue, red, Over, blue},
+		{transparent, blue, Over, blue},
+		{red, blue, In, red},
+		{blue, red, In, rgba(0, 0, 128, 128)},
+		{red, blue, Out, transparent},
+		{blue, transparent, Out, blue},
+		{red, blue, Atop, rgba(128, 0, 127, 255)},
+		{blue, red, Atop, rgba(0, 0, 128, 128)},
+		{red, blue, Xor, rgba(0, 0, 127, 127)},
+		{red, transparent, Xor, red},
+	}
+
+	for _, test := range cases {
+		found := test.source.Composite(test.destination, test.op)
+
+		if found != test.expected {
+			t.Errorf("%s with op %d onto %s: expected %#v but got %#v",
+				test.source, test.op, test.destination, test.expected, found)
+		}
+	}
+}
+
+func TestBlendModes(t *testing.T) {
+	gray := rgba(128, 128, 128, 255)
+	light := rgba(200, 100, 30, 255)
+
+	cases := []struct {
+		op       CompositeOp
+		expected Pixel
+	}{
+		{Multiply, rgba(100, 50, 15, 255)},
+		{Screen, rgba(228, 178, 143, 255)},
+		{Overlay, rgba(200, 101, 31, 255)},
+	}
+
+	for _, test := range cases {
+		found := light.Composite(gray, test.op)
+
+		// The blends keep 16-bit precision so only the bytes are compared
+		if premultipliedOf(&found) != premultipliedOf(&test.expected) {
+			t.Errorf("Op %d: expected %#v but got %#v", test.op, test.expected, found)
+		}
+	}
+
+	// A transparent source changes nothing, whatever the blend
+	for _, op := range []CompositeOp{Multiply, Screen, Overlay} {
+		if found := rgba(0, 0, 0, 0).Composite(light, op); found != light {
+			t.Errorf("Op %d with a transparent source: got %#v", op, found)
+		}
+	}
+}
+
+func TestCompositeImages(t *testing.T) {
+	background := numberedImage(t)
+	layer, _ := ParseImage([]byte{9, 9}, newHeader("Y", 1, "None"))
+
+	background.Composite(layer, image.Pt(2, 1), Over)
+
+	assertNumbers(t, "Over", background, [][]byte{{1, 2, 3}, {4, 5, 9}})
+
+	// Parts of the layer outside of the image are ignored
+	background.Composite(layer, image.Pt(0, -1), Over)
+
+	assertNumbers(t, "Over", background, [][]byte{{9, 2, 3}, {4, 5, 9}})
+
+	background.Composite(layer, image.Pt(1, 0), In)
+
+	assertNumbers(t, "In", background, [][]byte{{0, 9, 0}, {0, 9, 0}})
+
+	if _, _, _, alpha := background.At(0, 0).RGBA(); alpha != 0 {
+		t.Errorf("Pixel outside of the source was not cleared by In")
+	}
+}
@@ -0,0 +1,242 @@
+package rawimage
+
+import (
+	"encoding/binary"
+	"image"
+	"io"
+)
+
+// The container is a tiny self-describing wrapper around a payload:
+//
+//	magic    "FMIRAW"
+//	format   one length byte followed by Header.Format
+//	encoding one length byte followed by Header.Encoding
+//	width    big endian uint32, Header.LineWidth
+//	height   big endian uint32, number of rows
+//	palette  only for the indexed encodings, a big endian uint16 count
+//	         followed by the colours in the header format
+//	payload  the encoded pixels, exactly as ParseImage expects them
+//
+// Payloads in containers are always packed, top-down and interleaved,
+// WriteContainer ignores Header.Stride, Header.BottomUp and Header.Layout.
+//
+// The height is not part of Header but without it image.DecodeConfig would
+// have to decode the whole payload to find out how big the image is.
+const containerMagic = "FMIRAW"
+
+func init() {
+	image.RegisterFormat("fmiraw", containerMagic, decodeContainer,
+		decodeContainerConfig)
+}
+
+type containerHeader struct {
+	header Header
+	height uint32
+}
+
+// ReadContainer decodes an image stored in the container format. The payload
+// is streamed through a Decoder so only the decoded pixels are kept in memory.
+func ReadContainer(reader io.Reader) (*Image, error) {
+	return ReadContainerWithOptions(reader, Options{})
+}
+
+// ReadContainerWithOptions is ReadContainer with the parsing options and
+// limits of ParseImageWithOptions.
+func ReadContainerWithOptions(reader io.Reader, options Options) (*Image, error) {
+	container, err := readContainerHeader(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pixels := (int)(container.header.LineWidth) * (int)(container.height)
+
+	if err := options.Limits.resolved().checkPixels(pixels); err != nil {
+		return nil, err
+	}
+
+	decoder, headerError := NewDecoderWithOptions(reader, container.header,
+		options)
+
+	if headerError != nil {
+		return nil, headerError
+	}
+
+	img := new(Image)
+	img.header = container.header
+
+	for rows := uint32(0); rows < container.height; rows++ {
+		row, err := decoder.NextRow()
+
+		if err == io.EOF {
+			return nil, newImageError(BadContainer, "Not enough rows in the payload")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		img.data = append(img.data, row...)
+	}
+
+	return img, nil
+}
+
+// WriteContainer writes the image in the container format with its payload
+// encoded as described by header.
+func WriteContainer(writer io.Writer, img *Image, header Header) error {
+	header.Stride, header.BottomUp, header.Layout = 0, false, Interleaved
+
+	payload, err := Encode(img, header)
+
+	if err != nil {
+		return err
+	}
+
+	height := img.height()
+
+	out := []byte(containerMagic)
+	out = append(out, (byte)(len(header.Format)))
+	out = append(out, header.Format...)
+	out = append(out, (byte)(len(header.Encoding)))
+	out = append(out, header.Encoding...)
+	out = binary.BigEndian.AppendUint32(out, (uint32)(header.LineWidth))
+	out = binary.BigEndian.AppendUint32(out, (uint32)(height))
+
+	if isIndexed(header.Encoding) {
+		format, _ := parseFormat(header.Format)
+		out = binary.BigEndian.AppendUint16(out, (uint16)(len(header.Palette)))
+
+		for index := range header.Palette {
+			colour := make([]byte, format.pixelSize())
+			format.encode(&header.Palette[index], colour)
+			out = append(out, colour...)
+		}
+	}
+
+	if _, err := writer.Write(out); err != nil {
+		return err
+	}
+
+	_, err = writer.Write(payload)
+
+	return err
+}
+
+func readContainerHeader(reader io.Reader) (*containerHeader, error) {
+	magic := make([]byte, len(containerMagic))
+
+	if _, err := io.ReadFull(reader, magic); err != nil ||
+		string(magic) != containerMagic {
+		return nil, newImageError(BadContainer, "Not an image container")
+	}
+
+	format, err := readShortString(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	encoding, err := readShortString(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var dimensions [2]uint32
+
+	if err := binary.Read(reader, binary.BigEndian, &dimensions); err != nil {
+		return nil, newImageError(BadContainer, "Truncated container header")
+	}
+
+	container := &containerHeader{
+		header: Header{Format: format, LineWidth: (uint)(dimensions[0]),
+			Encoding: encoding},
+		height: dimensions[1],
+	}
+
+	if isIndexed(encoding) {
+		palette, err := readPalette(reader, format)
+
+		if err != nil {
+			return nil, err
+		}
+
+		container.header.Palette = palette
+	}
+
+	if headerError := isHeaderValid(container.header); headerError != nil {
+		return nil, headerError
+	}
+
+	return container, nil
+}
+
+func readPalette(reader io.Reader, formatName string) ([]Pixel, error) {
+	format, err := parseFormat(formatName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var count uint16
+
+	if err := binary.Read(reader, binary.BigEndian, &count); err != nil {
+		return nil, newImageError(BadContainer, "Truncated container palette")
+	}
+
+	colours := make([]byte, (int)(count)*format.pixelSize())
+
+	if _, err := io.ReadFull(reader, colours); err != nil {
+		return nil, newImageError(BadContainer, "Truncated container palette")
+	}
+
+	palette := make([]Pixel, count)
+	for index := range palette {
+		offset := index * format.pixelSize()
+		palette[index] = format.decode(colours[offset:offset+format.pixelSize()],
+			Options{})
+	}
+
+	return palette, nil
+}
+
+func readShortString(reader io.Reader) (string, error) {
+	var length [1]byte
+
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		return "", newImageError(BadContainer, "Truncated container header")
+	}
+
+	value := make([]byte, length[0])
+
+	if _, err := io.ReadFull(reader, value); err != nil {
+		return "", newImageError(BadContainer, "Truncated container header")
+	}
+
+	return string(value), nil
+}
+
+func decodeContainer(reader io.Reader) (image.Image, error) {
+	img, err := ReadContainer(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+func decodeContainerConfig(reader io.Reader) (image.Config, error) {
+	container, err := readContainerHeader(reader)
+
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: PixelModel,
+		Width:      (int)(container.header.LineWidth),
+		Height:     (int)(container.height),
+	}, nil
+}
@@ -0,0 +1,83 @@
+package rawimage
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestContainerRoundTripThroughImageDecode(t *testing.T) {
+	data := []byte{
+		0, 12, 244, 128, 14, 26, 52, 127,
+		31, 33, 41, 255, 31, 33, 41, 255,
+		1, 2, 3, 255, 1, 2, 3, 255,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+
+	var buffer bytes.Buffer
+
+	if err := WriteContainer(&buffer, picture, newHeader("BGRA", 2, "RLE")); err != nil {
+		t.Fatalf("Writing the container returned error: %s", err)
+	}
+
+	config, name, err := image.DecodeConfig(bytes.NewReader(buffer.Bytes()))
+
+	if err != nil {
+		t.Fatalf("Decoding the config returned error: %s", err)
+	}
+
+	if name != "fmiraw" || config.Width != 2 || config.Height != 3 {
+		t.Errorf("Wrong config: %s %dx%d", name, config.Width, config.Height)
+	}
+
+	decoded, name, err := image.Decode(&buffer)
+
+	if err != nil {
+		t.Fatalf("Decoding the image returned error: %s", err)
+	}
+
+	if name != "fmiraw" {
+		t.Errorf("Image was decoded as %s", name)
+	}
+
+	converted, ok := decoded.(*Image)
+
+	if !ok {
+		t.Fatalf("Decoded image was %T", decoded)
+	}
+
+	for index, pixel := range picture.data {
+		if found := converted.data[index]; pixel != found {
+			t.Errorf("Pixel %d: expected %s but got %s", index, pixel, found)
+		}
+	}
+}
+
+func TestContainerWithMissingRows(t *testing.T) {
+	picture, _ := ParseImage([]byte{1, 2, 3, 4, 5, 6}, newHeader("RGB", 1, "None"))
+
+	var buffer bytes.Buffer
+	WriteContainer(&buffer, picture, newHeader("RGB", 1, "None"))
+
+	truncated := buffer.Bytes()[:buffer.Len()-3]
+
+	if _, err := ReadContainer(bytes.NewReader(truncated)); err == nil {
+		t.Error("No error when the container payload has missing rows")
+	}
+}
+
+func TestContainerWithBrokenHeader(t *testing.T) {
+	broken := [][]byte{
+		[]byte("NOTRAW"),
+		[]byte("FMIRAW\x03RGB"),
+		[]byte("FMIRAW\x03RGX\x04None\x00\x00\x00\x01\x00\x00\x00\x01"),
+		[]byte("FMIRAW\x03RGB\x04None\x00\x00"),
+	}
+
+	for _, data := range broken {
+		if _, err := ReadContainer(bytes.NewReader(data)); err == nil {
+			t.Errorf("No error for broken container %q", data)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package rawimage
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// Decoder reads an image payload from an io.Reader one row at a time. Unlike
+// ParseImage it never holds more than a single row of pixels in memory, which
+// makes it usable for dumps far bigger than the available RAM. Planar layouts
+// are the exception, all of their planes have to be read before the first row.
+type Decoder struct {
+	header    Header
+	options   Options
+	limits    Limits
+	format    *pixelFormat
+	pixelSize int
+
+	// The encoded data, counting how much of it has been consumed
+	input *countingReader
+
+	// Stream of raw, unencoded pixel bytes in header.Format order
+	raw io.Reader
+
+	pixelBuffer []byte
+	padding     []byte // room for the bytes skipped after every row
+	planesRead  bool   // planar data has been read and interleaved already
+	pixels      int    // how many pixels have been decoded so far
+}
+
+func NewDecoder(reader io.Reader, header Header) (*Decoder, error) {
+	return NewDecoderWithOptions(reader, header, Options{})
+}
+
+func NewDecoderWithOptions(reader io.Reader, header Header,
+	options Options) (*Decoder, error) {
+
+	if headerError := isHeaderValid(header); headerError != nil {
+		return nil, headerError
+	}
+
+	limits := options.Limits.resolved()
+
+	if err := limits.checkWidth(header.LineWidth); err != nil {
+		return nil, err
+	}
+
+	if err := limits.checkStride(header.Stride); err != nil {
+		return nil, err
+	}
+
+	decoder := new(Decoder)
+	decoder.header = header
+	decoder.options = options
+	decoder.limits = limits
+	decoder.format, _ = parseFormat(header.Format)
+	decoder.pixelSize = decoder.format.pixelSize()
+	decoder.pixelBuffer = make([]byte, decoder.pixelSize)
+
+	rowBytes, stride := header.rowLayout(decoder.pixelSize)
+	decoder.padding = make([]byte, stride-rowBytes)
+	decoder.input = &countingReader{reader: bufio.NewReader(reader)}
+	decoder.planesRead = header.Layout == Interleaved
+
+	decodeEncoding, _ := lookupEncoding(header.Encoding)
+	decoder.raw = decodeEncoding(decoder.input, decoder.pixelSize, header)
+
+	return decoder, nil
+}
+
+// Returns the next full row of the image. When there are no more rows the
+// returned error is io.EOF. The rows come in the order they are stored, so
+// with Header.BottomUp the first one is the bottom row of the image. Broken
+// data is reported with an *ImageError as soon as it is detected.
+func (decoder *Decoder) NextRow() ([]Pixel, error) {
+	row := make([]Pixel, decoder.header.LineWidth)
+
+	for index := range row {
+		pixel, err := decoder.nextPixel()
+
+		if err == io.EOF && index == 0 {
+			return nil, io.EOF
+		}
+
+		if err == io.EOF {
+			return nil, decoder.locate(newImageError(PartialRow,
+				"Not enough data for a whole row"))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		row[index] = pixel
+	}
+
+	// The padding of the last row is often left out, so it is fine to run
+	// out of data while skipping it
+	if len(decoder.padding) > 0 {
+		_, err := io.ReadFull(decoder.raw, decoder.padding)
+
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, decoder.locate(err)
+		}
+	}
+
+	return row, nil
+}
+
+func (decoder *Decoder) nextPixel() (Pixel, error) {
+	if !decoder.planesRead {
+		if err := decoder.readPlanes(); err != nil {
+			return Pixel{}, err
+		}
+	}
+
+	_, err := io.ReadFull(decoder.raw, decoder.pixelBuffer)
+
+	if err == io.ErrUnexpectedEOF {
+		err = newImageError(TruncatedPixel, "Not enough data for pixel")
+	}
+
+	if err != nil {
+		return Pixel{}, decoder.locate(err)
+	}
+
+	decoder.pixels++
+
+	raw := (int64)(decoder.pixels) * (int64)(decoder.pixelSize)
+	if err := decoder.limits.checkExpansion(raw, decoder.input.count); err != nil {
+		return Pixel{}, decoder.locate(err)
+	}
+
+	return decoder.format.decode(decoder.pixelBuffer, decoder.options), nil
+}
+
+// Image errors get the position of the pixel which is being decoded, no matter
+// if they came from the decoder itself or from the encoding.
+func (decoder *Decoder) locate(err error) error {
+	var imageError *ImageError
+
+	if !errors.As(err, &imageError) {
+		return err
+	}
+
+	imageError.Offset = decoder.input.count
+	width := (int)(decoder.header.LineWidth)
+	imageError.X, imageError.Y = decoder.pixels%width, decoder.pixels/width
+
+	return err
+}
+
+// Encodings need single byte reads. All of them get the decoder input which
+// has them, but registered decoders may be called with anything.
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+func asByteReader(reader io.Reader) byteReader {
+	if buffered, ok := reader.(byteReader); ok {
+		return buffered
+	}
+	return bufio.NewReader(reader)
+}
+
+type countingReader struct {
+	reader *bufio.Reader
+	count  int64
+}
+
+func (counting *countingReader) Read(buffer []byte) (int, error) {
+	read, err := counting.reader.Read(buffer)
+	counting.count += (int64)(read)
+	return read, err
+}
+
+func (counting *countingReader) ReadByte() (byte, error) {
+	value, err := counting.reader.ReadByte()
+	if err == nil {
+		counting.count++
+	}
+	return value, err
+}
+
+// rleReader expands a run-length encoded stream into raw pixel bytes. Every run
+// is a single count byte followed by one pixel which is repeated count times.
+type rleReader struct {
+	reader    byteReader
+	pixel     []byte
+	remaining int // how many more times the current pixel has to be emitted
+	offset    int // how much of the current pixel has been emitted already
+}
+
+func newRLEReader(reader io.Reader, pixelSize int) io.Reader {
+	return &rleReader{reader: asByteReader(reader), pixel: make([]byte, pixelSize)}
+}
+
+func (rle *rleReader) Read(buffer []byte) (int, error) {
+	read := 0
+
+	for read < len(buffer) {
+		if rle.remaining == 0 {
+			if err := rle.nextRun(); err != nil {
+				if read > 0 && err == io.EOF {
+					return read, nil
+				}
+				return read, err
+			}
+			continue
+		}
+
+		copied := copy(buffer[read:], rle.pixel[rle.offset:])
+		read += copied
+		rle.offset += copied
+
+		if rle.offset == len(rle.pixel) {
+			rle.offset = 0
+			rle.remaining--
+		}
+	}
+
+	return read, nil
+}
+
+func (rle *rleReader) nextRun() error {
+	count, err := rle.reader.ReadByte()
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(rle.reader, rle.pixel); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return newImageError(TruncatedPixel, "Not enough data for pixel")
+		}
+		return err
+	}
+
+	rle.remaining = int(count)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoderYieldsRows(t *testing.T) {
+	data := []byte{
+		0, 12, 244, 127, 14, 26, 52, 127,
+		31, 33, 41, 255, 36, 133, 241, 255,
+	}
+
+	header := newHeader("RGBA", 2, "None")
+
+	decoder, headerError := NewDecoder(iotest.OneByteReader(bytes.NewReader(data)),
+		header)
+
+	if headerError != nil {
+		t.Fatalf("Creating the decoder returned error: %s", headerError)
+	}
+
+	firstRow, err := decoder.NextRow()
+
+	if err != nil {
+		t.Fatalf("Reading the first row returned error: %s", err)
+	}
+
+	if err := assertColor(&firstRow[1], 7, 13, 26); err != nil {
+		t.Error(err)
+	}
+
+	secondRow, err := decoder.NextRow()
+
+	if err != nil {
+		t.Fatalf("Reading the second row returned error: %s", err)
+	}
+
+	if err := assertColor(&secondRow[0], 31, 33, 41); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := decoder.NextRow(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last row but got %v", err)
+	}
+}
+
+func TestDecoderRLERunsSpanRows(t *testing.T) {
+	header := newHeader("RGB", 2, "RLE")
+
+	data := []byte{
+		3, 1, 2, 3,
+		1, 4, 5, 6,
+	}
+
+	decoder, _ := NewDecoder(bytes.NewReader(data), header)
+
+	firstRow, err := decoder.NextRow()
+
+	if err != nil {
+		t.Fatalf("Reading the first row returned error: %s", err)
+	}
+
+	secondRow, err := decoder.NextRow()
+
+	if err != nil {
+		t.Fatalf("Reading the second row returned error: %s", err)
+	}
+
+	if err := assertColor(&firstRow[1], 1, 2, 3); err != nil {
+		t.Error(err)
+	}
+
+	if err := assertColor(&secondRow[0], 1, 2, 3); err != nil {
+		t.Error(err)
+	}
+
+	if err := assertColor(&secondRow[1], 4, 5, 6); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecoderReportsErrorsWhenDetected(t *testing.T) {
+	rleHeader := newHeader("RGB", 1, "RLE")
+
+	decoder, _ := NewDecoder(bytes.NewReader([]byte{1, 1, 2, 3, 1, 4}), rleHeader)
+
+	if _, err := decoder.NextRow(); err != nil {
+		t.Errorf("The first row was complete but got error %s", err)
+	}
+
+	_, err := decoder.NextRow()
+
+	var imageError *ImageError
+
+	if !errors.As(err, &imageError) || imageError.Code != TruncatedPixel ||
+		imageError.Offset != 6 || imageError.X != 0 || imageError.Y != 1 {
+		t.Errorf("Expected error for not enough pixel data but got %v", err)
+	}
+
+	rowHeader := newHeader("RGB", 2, "None")
+
+	decoder, _ = NewDecoder(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9}),
+		rowHeader)
+
+	if _, err := decoder.NextRow(); err != nil {
+		t.Errorf("The first row was complete but got error %s", err)
+	}
+
+	_, err = decoder.NextRow()
+
+	if !errors.Is(err, ErrPartialRow) {
+		t.Errorf("Expected error for not enough row data but got %v", err)
+	}
+
+	expected := "Not enough data for a whole row at byte 9 (pixel 1, 1)"
+
+	if err.Error() != expected {
+		t.Errorf("Expected error message %q but got %q", expected, err)
+	}
+}
+
+func TestDecoderWithWrongHeader(t *testing.T) {
+	header := newHeader("RGBX", 2, "None")
+
+	if _, err := NewDecoder(bytes.NewReader(nil), header); err == nil {
+		t.Error("No error when creating a decoder with wrong header format")
+	}
+}
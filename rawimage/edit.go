@@ -0,0 +1,139 @@
+package rawimage
+
+import (
+	"image"
+)
+
+func (img *Image) SetPixel(x uint, y uint, pixel Pixel) error {
+	if err := img.checkBounds(x, y); err != nil {
+		return err
+	}
+
+	img.data[img.pixelIndex((int)(x), (int)(y))] = pixel
+
+	return nil
+}
+
+// SubImage returns the part of the image inside rect. It shares its pixels
+// with img so changes to either of them are seen in both. The sub image starts
+// at (0, 0) like every other Image.
+func (img *Image) SubImage(rect image.Rectangle) (*Image, error) {
+	if err := img.checkRectangle(rect); err != nil {
+		return nil, err
+	}
+
+	start := img.pixelIndex(rect.Min.X, rect.Min.Y)
+	end := img.pixelIndex(rect.Max.X-1, rect.Max.Y-1) + 1
+
+	sub := img.withWidth(rect.Dx())
+	sub.data = img.data[start:end:end]
+	sub.stride = img.rowStride()
+
+	return sub, nil
+}
+
+// Crop is SubImage with a copy of the pixels instead of shared ones
+func (img *Image) Crop(rect image.Rectangle) (*Image, error) {
+	sub, err := img.SubImage(rect)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cropped := img.withWidth(rect.Dx())
+	cropped.data = make([]Pixel, 0, rect.Dx()*rect.Dy())
+
+	for y := 0; y < sub.height(); y++ {
+		cropped.data = append(cropped.data, sub.row(y)...)
+	}
+
+	return cropped, nil
+}
+
+// FlipHorizontal returns a mirror copy of the image, left becomes right
+func (img *Image) FlipHorizontal() *Image {
+	width := img.width()
+	return img.transformed(width, img.height(), func(x, y int) (int, int) {
+		return width - 1 - x, y
+	})
+}
+
+// FlipVertical returns an upside down copy of the image
+func (img *Image) FlipVertical() *Image {
+	height := img.height()
+	return img.transformed(img.width(), height, func(x, y int) (int, int) {
+		return x, height - 1 - y
+	})
+}
+
+// Turns the image upside down in place
+func (img *Image) flipRows() {
+	for top, bottom := 0, img.height()-1; top < bottom; top, bottom = top+1,
+		bottom-1 {
+
+		topRow, bottomRow := img.row(top), img.row(bottom)
+		for x := range topRow {
+			topRow[x], bottomRow[x] = bottomRow[x], topRow[x]
+		}
+	}
+}
+
+// Rotate90 returns a copy of the image rotated clockwise
+func (img *Image) Rotate90() *Image {
+	height := img.height()
+	return img.transformed(height, img.width(), func(x, y int) (int, int) {
+		return y, height - 1 - x
+	})
+}
+
+func (img *Image) Rotate180() *Image {
+	width, height := img.width(), img.height()
+	return img.transformed(width, height, func(x, y int) (int, int) {
+		return width - 1 - x, height - 1 - y
+	})
+}
+
+// Rotate270 returns a copy of the image rotated counterclockwise
+func (img *Image) Rotate270() *Image {
+	width := img.width()
+	return img.transformed(img.height(), width, func(x, y int) (int, int) {
+		return width - 1 - y, x
+	})
+}
+
+// Builds a new width x height image. source tells for every pixel of the new
+// image which pixel of img goes there.
+func (img *Image) transformed(width, height int,
+	source func(x, y int) (int, int)) *Image {
+
+	result := img.withWidth(width)
+	result.data = make([]Pixel, 0, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sourceX, sourceY := source(x, y)
+			result.data = append(result.data,
+				img.data[img.pixelIndex(sourceX, sourceY)])
+		}
+	}
+
+	return result
+}
+
+// A new image with no pixels and the same header as img, except for the width
+func (img *Image) withWidth(width int) *Image {
+	result := new(Image)
+	result.header = img.header
+	result.header.LineWidth = (uint)(width)
+	return result
+}
+
+func (img *Image) checkRectangle(rect image.Rectangle) error {
+	if rect.Empty() || !rect.In(img.Bounds()) {
+		err := newImageError(OutOfRange, "Rectangle "+rect.String()+
+			" is not inside the image")
+		err.X, err.Y = rect.Min.X, rect.Min.Y
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package rawimage
+
+import (
+	"errors"
+	"image"
+	"reflect"
+	"testing"
+)
+
+// A 3x2 grayscale image with pixels numbered from 1 to 6 row by row
+func numberedImage(t *testing.T) *Image {
+	picture, err := ParseImage([]byte{1, 2, 3, 4, 5, 6}, newHeader("Y", 3, "None"))
+
+	if err != nil {
+		t.Fatalf("Parsing the image returned error: %s", err)
+	}
+
+	return picture
+}
+
+func gray(value byte) Pixel {
+	return Pixel{Red: value, Green: value, Blue: value, Alpha: 255}
+}
+
+// The red channel of every pixel, row by row
+func numbers(picture *Image) [][]byte {
+	var rows [][]byte
+
+	for y := 0; y < picture.height(); y++ {
+		var row []byte
+		for _, pixel := range picture.row(y) {
+			row = append(row, pixel.Red)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+func assertNumbers(t *testing.T, what string, picture *Image, expected [][]byte) {
+	if found := numbers(picture); !reflect.DeepEqual(found, expected) {
+		t.Errorf("%s: expected %v but got %v", what, expected, found)
+	}
+}
+
+func TestSetPixel(t *testing.T) {
+	picture := numberedImage(t)
+
+	if err := picture.SetPixel(2, 1, gray(9)); err != nil {
+		t.Fatalf("Setting the pixel returned error: %s", err)
+	}
+
+	assertNumbers(t, "SetPixel", picture, [][]byte{{1, 2, 3}, {4, 5, 9}})
+
+	if err := picture.SetPixel(3, 0, Pixel{}); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected out of range error but got %v", err)
+	}
+
+	if err := picture.SetPixel(0, 2, Pixel{}); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected out of range error but got %v", err)
+	}
+}
+
+func TestSubImageSharesPixels(t *testing.T) {
+	picture := numberedImage(t)
+
+	sub, err := picture.SubImage(image.Rect(1, 0, 3, 2))
+
+	if err != nil {
+		t.Fatalf("Getting the sub image returned error: %s", err)
+	}
+
+	assertNumbers(t, "SubImage", sub, [][]byte{{2, 3}, {5, 6}})
+
+	if bounds := sub.Bounds(); bounds != image.Rect(0, 0, 2, 2) {
+		t.Errorf("Wrong sub image bounds: %v", bounds)
+	}
+
+	sub.SetPixel(0, 1, gray(9))
+
+	assertNumbers(t, "parent", picture, [][]byte{{1, 2, 3}, {4, 9, 6}})
+
+	if _, err := sub.InspectPixel(2, 0); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("Expected out of range error but got %v", err)
+	}
+
+	subSub, _ := sub.SubImage(image.Rect(1, 1, 2, 2))
+
+	assertNumbers(t, "sub image of a sub image", subSub, [][]byte{{6}})
+
+	encoded, _ := Encode(sub, newHeader("Y", 2, "None"))
+
+	if expected := []byte{2, 3, 9, 6}; !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Encoding the sub image: expected %v but got %v", expected, encoded)
+	}
+}
+
+func TestCropCopiesPixels(t *testing.T) {
+	picture := numberedImage(t)
+
+	cropped, err := picture.Crop(image.Rect(0, 1, 2, 2))
+
+	if err != nil {
+		t.Fatalf("Cropping returned error: %s", err)
+	}
+
+	assertNumbers(t, "Crop", cropped, [][]byte{{4, 5}})
+
+	cropped.SetPixel(0, 0, gray(9))
+
+	assertNumbers(t, "parent", picture, [][]byte{{1, 2, 3}, {4, 5, 6}})
+}
+
+func TestWrongRectangles(t *testing.T) {
+	picture := numberedImage(t)
+
+	for _, rect := range []image.Rectangle{
+		image.Rect(0, 0, 4, 1),
+		image.Rect(-1, 0, 1, 1),
+		image.Rect(1, 1, 1, 2),
+	} {
+		if _, err := picture.SubImage(rect); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("SubImage %v: expected out of range error but got %v", rect, err)
+		}
+
+		if _, err := picture.Crop(rect); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Crop %v: expected out of range error but got %v", rect, err)
+		}
+	}
+}
+
+func TestFlipsAndRotations(t *testing.T) {
+	picture := numberedImage(t)
+
+	assertNumbers(t, "FlipHorizontal", picture.FlipHorizontal(),
+		[][]byte{{3, 2, 1}, {6, 5, 4}})
+	assertNumbers(t, "FlipVertical", picture.FlipVertical(),
+		[][]byte{{4, 5, 6}, {1, 2, 3}})
+	assertNumbers(t, "Rotate90", picture.Rotate90(),
+		[][]byte{{4, 1}, {5, 2}, {6, 3}})
+	assertNumbers(t, "Rotate180", picture.Rotate180(),
+		[][]byte{{6, 5, 4}, {3, 2, 1}})
+	assertNumbers(t, "Rotate270", picture.Rotate270(),
+		[][]byte{{3, 6}, {2, 5}, {1, 4}})
+
+	sub, _ := picture.SubImage(image.Rect(1, 0, 3, 2))
+
+	assertNumbers(t, "Rotate90 of a sub image", sub.Rotate90(),
+		[][]byte{{5, 2}, {6, 3}})
+}
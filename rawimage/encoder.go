@@ -0,0 +1,79 @@
+package rawimage
+
+import (
+	"bytes"
+)
+
+// The RLE count is a single byte so no run can be longer than this
+const maxRunLength = 255
+
+// Encode serialises the image into raw bytes in the format and encoding
+// described by header. Parsing the result with the same header gives back
+// the same pixels.
+//
+// Rows are padded with zeros up to header.Stride and written from the bottom
+// up when header.BottomUp is set.
+//
+// For the indexed encodings every pixel is replaced by the closest colour of
+// header.Palette. Quantise can make a palette which fits the image.
+func Encode(img *Image, header Header) ([]byte, error) {
+	if headerError := isHeaderValid(header); headerError != nil {
+		return nil, headerError
+	}
+
+	format, _ := parseFormat(header.Format)
+
+	if header.LineWidth != img.header.LineWidth {
+		return nil, newImageError(BadDimensions,
+			"Header line width does not match the image")
+	}
+
+	if header.BottomUp {
+		img = img.FlipVertical()
+	}
+
+	pixelSize := format.pixelSize()
+	_, stride := header.rowLayout(pixelSize)
+	raw := make([]byte, stride*img.height())
+
+	for y := 0; y < img.height(); y++ {
+		row := img.row(y)
+		for x := range row {
+			format.encode(&row[x], raw[y*stride+x*pixelSize:])
+		}
+	}
+
+	if header.Layout != Interleaved {
+		raw = rearrange(raw, format, header.Layout, false)
+	}
+
+	encode, ok := lookupEncoder(header.Encoding)
+
+	if !ok {
+		return nil, newImageError(BadEncoding, "There is no encoder for "+header.Encoding)
+	}
+
+	return encode(raw, pixelSize, header), nil
+}
+
+func encodeRLE(raw []byte, pixelSize int) []byte {
+	var out []byte
+
+	for start := 0; start < len(raw); {
+		pixel := raw[start : start+pixelSize]
+		end := start + pixelSize
+		count := 1
+
+		for count < maxRunLength && end < len(raw) &&
+			bytes.Equal(pixel, raw[end:end+pixelSize]) {
+			end += pixelSize
+			count++
+		}
+
+		out = append(out, byte(count))
+		out = append(out, pixel...)
+		start = end
+	}
+
+	return out
+}
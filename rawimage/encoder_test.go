@@ -0,0 +1,115 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeRoundTripsInEveryFormat(t *testing.T) {
+	data := []byte{
+		0, 12, 244, 128, 14, 26, 52, 127,
+		31, 33, 41, 255, 36, 133, 241, 0,
+		31, 33, 41, 255, 31, 33, 41, 255,
+	}
+
+	picture, parseError := ParseImage(data, newHeader("RGBA", 2, "None"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	formats := []string{"RGBA", "ARGB", "BGRA", "GBAR", "RGB", "BGR", "GRB"}
+
+	for _, format := range formats {
+		for _, encoding := range []string{"None", "RLE"} {
+			header := newHeader(format, 2, encoding)
+
+			encoded, err := Encode(picture, header)
+
+			if err != nil {
+				t.Errorf("Encoding as %s/%s returned error: %s", format, encoding, err)
+				continue
+			}
+
+			decoded, parseError := ParseImage(encoded, header)
+
+			if parseError != nil {
+				t.Errorf("Parsing %s/%s returned error: %s", format, encoding,
+					parseError)
+				continue
+			}
+
+			for index := range picture.data {
+				original, found := picture.data[index], decoded.data[index]
+				if original.Red != found.Red || original.Green != found.Green ||
+					original.Blue != found.Blue {
+					t.Errorf("%s/%s pixel %d: expected %s but got %s", format,
+						encoding, index, original, found)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeRLESplitsLongRuns(t *testing.T) {
+	data := bytes.Repeat([]byte{1, 2, 3}, 600)
+	header := newHeader("RGB", 600, "None")
+
+	picture, _ := ParseImage(data, header)
+
+	encoded, err := Encode(picture, newHeader("BGR", 600, "RLE"))
+
+	if err != nil {
+		t.Fatalf("Encoding returned error: %s", err)
+	}
+
+	expected := []byte{255, 3, 2, 1, 255, 3, 2, 1, 90, 3, 2, 1}
+
+	if !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+}
+
+func TestEncodeRGBImageWithAlpha(t *testing.T) {
+	picture, _ := ParseImage([]byte{1, 2, 3}, newHeader("RGB", 1, "None"))
+
+	encoded, _ := Encode(picture, newHeader("ARGB", 1, "None"))
+
+	if expected := []byte{255, 1, 2, 3}; !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+}
+
+func TestEncodeWithWrongHeader(t *testing.T) {
+	picture, _ := ParseImage([]byte{1, 2, 3}, newHeader("RGB", 1, "None"))
+
+	if _, err := Encode(picture, newHeader("RGX", 1, "None")); err == nil {
+		t.Error("No error when encoding with wrong format")
+	}
+
+	if _, err := Encode(picture, newHeader("RGB", 1, "LZW")); err == nil {
+		t.Error("No error when encoding with wrong encoding")
+	}
+
+	if _, err := Encode(picture, newHeader("RGB", 3, "None")); !errors.Is(err,
+		ErrBadDimensions) {
+		t.Errorf("Expected bad dimensions for a different line width but got %v",
+			err)
+	}
+}
+
+func TestAlphaUnblendIsStable(t *testing.T) {
+	for alpha := 0; alpha < 256; alpha++ {
+		for colour := 0; colour < 256; colour++ {
+			blended := alphaBlend(byte(colour), byte(alpha))
+			again := alphaBlend(alphaUnblend(blended, byte(alpha)), byte(alpha))
+
+			if blended != again {
+				t.Fatalf("Colour %d with alpha %d blends to %d and then to %d",
+					colour, alpha, blended, again)
+			}
+		}
+	}
+}
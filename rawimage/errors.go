@@ -0,0 +1,83 @@
+package rawimage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode tells what kind of problem an ImageError is about
+type ErrorCode int
+
+const (
+	BadFormat ErrorCode = iota + 1
+	BadEncoding
+	TruncatedPixel
+	PartialRow
+	OutOfRange
+	BadContainer
+	BadDimensions
+	LimitExceeded
+)
+
+// Sentinel errors for every ErrorCode. Use them with errors.Is:
+//
+//	if errors.Is(err, rawimage.ErrTruncatedPixel) { ... }
+var (
+	ErrBadFormat      = errors.New("bad header format")
+	ErrBadEncoding    = errors.New("bad encoding")
+	ErrTruncatedPixel = errors.New("not enough data for a pixel")
+	ErrPartialRow     = errors.New("not enough data for a whole row")
+	ErrOutOfRange     = errors.New("pixel out of range")
+	ErrBadContainer   = errors.New("bad image container")
+	ErrBadDimensions  = errors.New("bad image dimensions")
+	ErrLimitExceeded  = errors.New("image limit exceeded")
+)
+
+var sentinels = map[ErrorCode]error{
+	BadFormat:      ErrBadFormat,
+	BadEncoding:    ErrBadEncoding,
+	TruncatedPixel: ErrTruncatedPixel,
+	PartialRow:     ErrPartialRow,
+	OutOfRange:     ErrOutOfRange,
+	BadContainer:   ErrBadContainer,
+	BadDimensions:  ErrBadDimensions,
+	LimitExceeded:  ErrLimitExceeded,
+}
+
+// ImageError is the error returned for everything which can go wrong with an
+// image. Get it out of an error with errors.As.
+type ImageError struct {
+	Code    ErrorCode
+	Message string
+
+	// Position in the encoded data where decoding failed. It is -1 when the
+	// error has nothing to do with the data.
+	Offset int64
+
+	// The pixel which could not be decoded or accessed. Only meaningful for
+	// TruncatedPixel, PartialRow and OutOfRange.
+	X, Y int
+}
+
+func (e *ImageError) Error() string {
+	message := e.Message
+
+	if e.Offset >= 0 {
+		message += fmt.Sprintf(" at byte %d", e.Offset)
+	}
+
+	switch e.Code {
+	case TruncatedPixel, PartialRow, OutOfRange:
+		message += fmt.Sprintf(" (pixel %d, %d)", e.X, e.Y)
+	}
+
+	return message
+}
+
+func (e *ImageError) Unwrap() error {
+	return sentinels[e.Code]
+}
+
+func newImageError(code ErrorCode, message string) *ImageError {
+	return &ImageError{Code: code, Message: message, Offset: -1}
+}
@@ -0,0 +1,60 @@
+package rawimage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorsMatchTheirSentinels(t *testing.T) {
+	cases := []struct {
+		data     []byte
+		header   Header
+		sentinel error
+	}{
+		{nil, newHeader("RGBX", 1, "None"), ErrBadFormat},
+		{nil, newHeader("RGB", 1, "FooBar"), ErrBadEncoding},
+		{[]byte{1, 2}, newHeader("RGB", 1, "None"), ErrTruncatedPixel},
+		{[]byte{1, 2, 3}, newHeader("RGB", 2, "None"), ErrPartialRow},
+	}
+
+	for _, test := range cases {
+		_, err := ParseImage(test.data, test.header)
+
+		if !errors.Is(err, test.sentinel) {
+			t.Errorf("Parsing %v with %v: expected %q but got %v", test.data,
+				test.header, test.sentinel, err)
+		}
+
+		for _, other := range []error{ErrBadFormat, ErrBadEncoding,
+			ErrTruncatedPixel, ErrPartialRow, ErrOutOfRange} {
+			if other != test.sentinel && errors.Is(err, other) {
+				t.Errorf("Error %v matched %q as well", err, other)
+			}
+		}
+	}
+}
+
+func TestSuccessfulParseReturnsNilInterface(t *testing.T) {
+	var err error
+
+	_, err = ParseImage([]byte{1, 2, 3}, newHeader("RGB", 1, "None"))
+
+	if err != nil {
+		t.Errorf("Expected a nil error but got %#v", err)
+	}
+}
+
+func TestInspectPixelOutOfRange(t *testing.T) {
+	picture, _ := ParseImage([]byte{1, 2, 3, 4, 5, 6}, newHeader("RGB", 1, "None"))
+
+	for _, point := range [][2]uint{{0, 2}, {1, 0}} {
+		_, err := picture.InspectPixel(point[0], point[1])
+
+		var imageError *ImageError
+
+		if !errors.As(err, &imageError) || imageError.Code != OutOfRange ||
+			imageError.X != (int)(point[0]) || imageError.Y != (int)(point[1]) {
+			t.Errorf("Expected out of range error for %v but got %v", point, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package rawimage
+
+import (
+	"encoding/binary"
+	"image/png"
+	"io"
+)
+
+// WritePNG writes img as a PNG. Opaque images are written without alpha.
+func WritePNG(writer io.Writer, img *Image) error {
+	return png.Encode(writer, img)
+}
+
+// Sizes of the BMP file header and of the BITMAPV4HEADER which follows it.
+// The V4 header is the oldest one which can describe an alpha channel.
+const (
+	bmpFileHeaderSize = 14
+	bmpInfoHeaderSize = 108
+)
+
+// WriteBMP writes img as an uncompressed top-down 32-bit BMP. The colour is
+// straight with alpha in the fourth byte of every pixel, which is how the
+// Windows BITMAPV4HEADER describes it.
+func WriteBMP(writer io.Writer, img *Image) error {
+	width, height := img.width(), img.height()
+	dataSize := width * height * 4
+	offset := bmpFileHeaderSize + bmpInfoHeaderSize
+
+	out := make([]byte, 0, offset+dataSize)
+
+	out = append(out, 'B', 'M')
+	out = binary.LittleEndian.AppendUint32(out, (uint32)(offset+dataSize))
+	out = binary.LittleEndian.AppendUint32(out, 0) // reserved
+	out = binary.LittleEndian.AppendUint32(out, (uint32)(offset))
+
+	out = binary.LittleEndian.AppendUint32(out, bmpInfoHeaderSize)
+	out = binary.LittleEndian.AppendUint32(out, (uint32)(width))
+	// A negative height means the rows go from the top down
+	out = binary.LittleEndian.AppendUint32(out, (uint32)(-(int32)(height)))
+	out = binary.LittleEndian.AppendUint16(out, 1)  // planes
+	out = binary.LittleEndian.AppendUint16(out, 32) // bits per pixel
+	out = binary.LittleEndian.AppendUint32(out, 3)  // BI_BITFIELDS
+	out = binary.LittleEndian.AppendUint32(out, (uint32)(dataSize))
+	out = binary.LittleEndian.AppendUint32(out, 2835) // 72 DPI horizontally
+	out = binary.LittleEndian.AppendUint32(out, 2835) // and vertically
+	out = binary.LittleEndian.AppendUint32(out, 0)    // palette colours
+	out = binary.LittleEndian.AppendUint32(out, 0)    // important colours
+
+	// Red, green, blue and alpha masks
+	out = binary.LittleEndian.AppendUint32(out, 0x00ff0000)
+	out = binary.LittleEndian.AppendUint32(out, 0x0000ff00)
+	out = binary.LittleEndian.AppendUint32(out, 0x000000ff)
+	out = binary.LittleEndian.AppendUint32(out, 0xff000000)
+
+	out = append(out, "BGRs"...) // LCS_sRGB, little endian
+	// The endpoints and gamma are unused with sRGB
+	out = append(out, make([]byte, 36+12)...)
+
+	samples, err := Encode(img, Header{Format: "BGRA",
+		LineWidth: img.header.LineWidth, Encoding: "None"})
+
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(append(out, samples...))
+
+	return err
+}
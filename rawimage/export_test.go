@@ -0,0 +1,65 @@
+package rawimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"testing"
+)
+
+func TestWritePNG(t *testing.T) {
+	data := []byte{255, 0, 0, 255, 0, 0, 255, 128}
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+
+	var buffer bytes.Buffer
+
+	if err := WritePNG(&buffer, picture); err != nil {
+		t.Fatalf("Writing returned error: %s", err)
+	}
+
+	decoded, err := png.Decode(&buffer)
+
+	if err != nil {
+		t.Fatalf("Decoding the PNG returned error: %s", err)
+	}
+
+	for x := 0; x < 2; x++ {
+		r, g, b, a := decoded.At(x, 0).RGBA()
+		er, eg, eb, ea := picture.At(x, 0).RGBA()
+
+		if r>>8 != er>>8 || g>>8 != eg>>8 || b>>8 != eb>>8 || a>>8 != ea>>8 {
+			t.Errorf("Pixel %d differs after PNG", x)
+		}
+	}
+}
+
+func TestWriteBMP(t *testing.T) {
+	data := []byte{255, 0, 0, 255, 0, 0, 255, 128, 1, 2, 3, 0, 4, 5, 6, 255}
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+
+	var buffer bytes.Buffer
+
+	if err := WriteBMP(&buffer, picture); err != nil {
+		t.Fatalf("Writing returned error: %s", err)
+	}
+
+	bmp := buffer.Bytes()
+
+	if string(bmp[:2]) != "BM" || len(bmp) != 14+108+16 {
+		t.Fatalf("Wrong BMP magic or size: %q, %d bytes", bmp[:2], len(bmp))
+	}
+
+	if size := binary.LittleEndian.Uint32(bmp[2:]); size != (uint32)(len(bmp)) {
+		t.Errorf("File size in the header is %d", size)
+	}
+
+	if height := (int32)(binary.LittleEndian.Uint32(bmp[22:])); height != -2 {
+		t.Errorf("Expected a top-down height of -2 but got %d", height)
+	}
+
+	expected := []byte{0, 0, 255, 255, 255, 0, 0, 128, 0, 0, 0, 0, 6, 5, 4, 255}
+
+	if !bytes.Equal(bmp[14+108:], expected) {
+		t.Errorf("Expected pixels %v but got %v", expected, bmp[14+108:])
+	}
+}
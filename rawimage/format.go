@@ -0,0 +1,300 @@
+package rawimage
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// pixelFormat is the parsed form of Header.Format. The format is a permutation
+// of R, G, B and an optional A, or a grayscale Y with an optional A, followed
+// by an optional "16" for two bytes per channel and then an optional "BE" or
+// "LE" byte order. 16-bit channels are big endian unless told otherwise, so
+// "RGBA16" and "RGBA16BE" are the same thing.
+type pixelFormat struct {
+	channels string
+	depth    int // bytes per channel
+	order    binary.ByteOrder
+	alpha    bool
+}
+
+func parseFormat(format string) (*pixelFormat, error) {
+	parsed := &pixelFormat{channels: format, depth: 1, order: binary.BigEndian}
+
+	explicitOrder := true
+
+	switch {
+	case strings.HasSuffix(parsed.channels, "LE"):
+		parsed.order = binary.LittleEndian
+	case strings.HasSuffix(parsed.channels, "BE"):
+		parsed.order = binary.BigEndian
+	default:
+		explicitOrder = false
+	}
+
+	if explicitOrder {
+		parsed.channels = parsed.channels[:len(parsed.channels)-2]
+	}
+
+	if strings.HasSuffix(parsed.channels, "16") {
+		parsed.channels = parsed.channels[:len(parsed.channels)-2]
+		parsed.depth = 2
+	} else if explicitOrder {
+		return nil, newImageError(BadFormat, "Byte order is only allowed for 16-bit formats")
+	}
+
+	formatLen := len(parsed.channels)
+
+	if formatLen < 1 || formatLen > 4 {
+		return nil, newImageError(BadFormat, "Header is too long or too short")
+	}
+
+	formatInt := 0
+
+	for _, char := range parsed.channels {
+		switch char {
+		case 'R':
+			formatInt += 10
+		case 'B':
+			formatInt += 100
+		case 'G':
+			formatInt += 1000
+		case 'A':
+			formatInt += 1
+		case 'Y':
+			formatInt += 10000
+		default:
+			return nil, newImageError(BadFormat, "Wrong letter in header format")
+		}
+	}
+
+	switch formatInt {
+	case 1110, 1111, 10000, 10001:
+	default:
+		return nil, newImageError(BadFormat,
+			"Header does not have red, green and blue or a luminance component")
+	}
+
+	parsed.alpha = formatInt%10 == 1
+
+	return parsed, nil
+}
+
+func (format *pixelFormat) pixelSize() int {
+	return len(format.channels) * format.depth
+}
+
+// Builds a pixel out of one pixel worth of raw bytes
+func (format *pixelFormat) decode(raw []byte, options Options) Pixel {
+	if format.depth == 2 {
+		return format.decode16(raw, options)
+	}
+
+	// Formats without alpha are fully opaque
+	pixel := Pixel{Alpha: 255}
+
+	for formatIndex, colourIntesity := range raw {
+		switch format.channels[formatIndex] {
+		case 'R':
+			pixel.Red = colourIntesity
+		case 'G':
+			pixel.Green = colourIntesity
+		case 'B':
+			pixel.Blue = colourIntesity
+		case 'Y':
+			pixel.Red = colourIntesity
+			pixel.Green = colourIntesity
+			pixel.Blue = colourIntesity
+		case 'A':
+			pixel.Alpha = colourIntesity
+			pixel.needsPremultiply = true
+		}
+	}
+
+	if !format.alpha {
+		return pixel
+	}
+
+	switch options.Alpha {
+	case StraightAlpha:
+		pixel.needsPremultiply = false
+		pixel.straight = true
+		return pixel
+	case BothAlpha:
+		pixel.keepsStraight = true
+		pixel.straightWide = channels16{
+			(uint16)(pixel.Red) * 0x101,
+			(uint16)(pixel.Green) * 0x101,
+			(uint16)(pixel.Blue) * 0x101,
+			(uint16)(pixel.Alpha) * 0x101,
+		}
+	}
+
+	pixel.premultiply(options.Blend)
+
+	return pixel
+}
+
+func (format *pixelFormat) decode16(raw []byte, options Options) Pixel {
+	var red, green, blue uint16
+	alpha := (uint16)(0xffff)
+
+	for formatIndex, colour := range []byte(format.channels) {
+		colourIntesity := format.order.Uint16(raw[formatIndex*2:])
+
+		switch colour {
+		case 'R':
+			red = colourIntesity
+		case 'G':
+			green = colourIntesity
+		case 'B':
+			blue = colourIntesity
+		case 'Y':
+			red, green, blue = colourIntesity, colourIntesity, colourIntesity
+		case 'A':
+			alpha = colourIntesity
+		}
+	}
+
+	if !format.alpha {
+		return newPixel16(red, green, blue, alpha)
+	}
+
+	if options.Alpha == StraightAlpha {
+		pixel := newPixel16(red, green, blue, alpha)
+		pixel.straight = true
+		return pixel
+	}
+
+	straight := channels16{red, green, blue, alpha}
+
+	blend := options.Blend.blend16
+
+	pixel := newPixel16(blend(red, alpha), blend(green, alpha), blend(blue, alpha),
+		alpha)
+
+	if options.Alpha == BothAlpha {
+		pixel.keepsStraight = true
+		pixel.straightWide = straight
+	}
+
+	return pixel
+}
+
+// Writes the pixel in format order into out. Whenever alpha is written the
+// colours have to be straight, or parsing would premultiply them a second time.
+// Without alpha the premultiplied colour is what the pixel looks like.
+func (format *pixelFormat) encode(pixel *Pixel, out []byte) {
+	if format.depth == 2 {
+		format.encode16(pixel, out)
+		return
+	}
+
+	red, green, blue, alpha := pixel.Premultiplied()
+
+	if format.alpha {
+		red, green, blue, alpha = pixel.Straight()
+	}
+
+	for index, colour := range []byte(format.channels) {
+		switch colour {
+		case 'R':
+			out[index] = red
+		case 'G':
+			out[index] = green
+		case 'B':
+			out[index] = blue
+		case 'Y':
+			out[index] = (byte)(luminance((uint32)(red), (uint32)(green),
+				(uint32)(blue)))
+		case 'A':
+			out[index] = alpha
+		}
+	}
+}
+
+func (format *pixelFormat) encode16(pixel *Pixel, out []byte) {
+	red, green, blue, alpha := pixel.RGBA64()
+
+	if format.alpha {
+		red, green, blue, alpha = pixel.Straight64()
+	}
+
+	for index, colour := range []byte(format.channels) {
+		var value uint16
+
+		switch colour {
+		case 'R':
+			value = red
+		case 'G':
+			value = green
+		case 'B':
+			value = blue
+		case 'Y':
+			value = (uint16)(luminance((uint32)(red), (uint32)(green),
+				(uint32)(blue)))
+		case 'A':
+			value = alpha
+		}
+
+		format.order.PutUint16(out[index*2:], value)
+	}
+}
+
+// Builds a pixel out of premultiplied 16-bit channels. The pixel is marked
+// as deep only when the bytes cannot hold its colour exactly, so colours which
+// came from 8-bit sources compare equal no matter how they were produced.
+func newPixel16(red, green, blue, alpha uint16) Pixel {
+	pixel := Pixel{
+		Red:   (byte)(red >> 8),
+		Green: (byte)(green >> 8),
+		Blue:  (byte)(blue >> 8),
+		Alpha: (byte)(alpha >> 8),
+	}
+
+	for _, channel := range []uint16{red, green, blue, alpha} {
+		if channel != (channel>>8)*0x101 {
+			pixel.deep = true
+		}
+	}
+
+	if pixel.deep {
+		pixel.wide = channels16{red, green, blue, alpha}
+	}
+
+	return pixel
+}
+
+// Same weights as color.GrayModel. They add up to 1<<16 so gray pixels keep
+// their exact value, whatever the channel depth is.
+func luminance(red, green, blue uint32) uint32 {
+	return (19595*red + 38470*green + 7471*blue + 1<<15) >> 16
+}
+
+// The inverse of alphaBlend, as close as a byte allows
+func alphaUnblend(colour byte, alpha byte) byte {
+	if alpha == 0 {
+		return 0
+	}
+
+	unblended := ((int)(colour)*255 + (int)(alpha)/2) / (int)(alpha)
+
+	if unblended > 255 {
+		return 255
+	}
+
+	return (byte)(unblended)
+}
+
+func alphaUnblend16(colour uint16, alpha uint16) uint16 {
+	if alpha == 0 {
+		return 0
+	}
+
+	unblended := ((uint32)(colour)*65535 + (uint32)(alpha)/2) / (uint32)(alpha)
+
+	if unblended > 65535 {
+		return 65535
+	}
+
+	return (uint16)(unblended)
+}
@@ -0,0 +1,208 @@
+package rawimage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func assertColor16(t *testing.T, pixel *Pixel, rgba ...uint16) {
+	r, g, b, a := pixel.RGBA64()
+
+	if found := []uint16{r, g, b, a}; !reflect.DeepEqual(found, rgba) {
+		t.Errorf("Wrong 16-bit colour: expected %v, got %v", rgba, found)
+	}
+}
+
+func TestBasicRGB16Call(t *testing.T) {
+	data := []byte{
+		0x12, 0x34, 0x00, 0xff, 0xab, 0xcd,
+	}
+
+	picture, parseError := ParseImage(data, newHeader("RGB16", 1, "None"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	pixel, _ := picture.InspectPixel(0, 0)
+
+	assertColor16(t, pixel, 0x1234, 0x00ff, 0xabcd, 0xffff)
+
+	if err := assertColor(pixel, 0x12, 0x00, 0xab); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLittleEndianBGRA16Call(t *testing.T) {
+	data := []byte{
+		0xff, 0xff, 0x00, 0x00, 0xff, 0xff, 0x00, 0x80,
+	}
+
+	picture, parseError := ParseImage(data, newHeader("BGRA16LE", 1, "None"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	pixel, _ := picture.InspectPixel(0, 0)
+
+	// Alpha is 0x8000, just above a half, and premultiplied at 16 bits
+	assertColor16(t, pixel, 0x8000, 0x0000, 0x8000, 0x8000)
+}
+
+func TestRLEWith16BitFormat(t *testing.T) {
+	data := []byte{
+		2, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06,
+	}
+
+	picture, parseError := ParseImage(data, newHeader("RGB16BE", 2, "RLE"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	pixel, _ := picture.InspectPixel(1, 0)
+
+	assertColor16(t, pixel, 0x0102, 0x0304, 0x0506, 0xffff)
+}
+
+func TestEncode16BitRoundTrip(t *testing.T) {
+	data := []byte{
+		0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xff, 0xff,
+		0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0x80, 0x01,
+	}
+
+	header := newHeader("RGBA16", 2, "None")
+	picture, _ := ParseImage(data, header)
+
+	for _, format := range []string{"ARGB16LE", "BGRA16", "RGB16LE"} {
+		other := newHeader(format, 2, "RLE")
+
+		encoded, err := Encode(picture, other)
+
+		if err != nil {
+			t.Fatalf("Encoding as %s returned error: %s", format, err)
+		}
+
+		decoded, _ := ParseImage(encoded, other)
+
+		expected, _ := picture.InspectPixel(1, 0)
+		found, _ := decoded.InspectPixel(1, 0)
+
+		if format == "RGB16LE" {
+			// There is no alpha to keep so only the premultiplied colour survives
+			r, g, b, _ := expected.RGBA64()
+			assertColor16(t, found, r, g, b, 0xffff)
+			continue
+		}
+
+		if *expected != *found {
+			t.Errorf("%s: expected %#v but got %#v", format, *expected, *found)
+		}
+	}
+
+	back, _ := Encode(picture, header)
+
+	if first := back[:8]; !reflect.DeepEqual(first, data[:8]) {
+		t.Errorf("Opaque pixel was not encoded exactly: %v", first)
+	}
+}
+
+func TestEightBitPixelsAreNotDeep(t *testing.T) {
+	data := []byte{
+		0x12, 0x12, 0x34, 0x34, 0x56, 0x56,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGB16", 1, "None"))
+	eightBit, _ := ParseImage([]byte{0x12, 0x34, 0x56}, newHeader("RGB", 1, "None"))
+
+	if picture.data[0] != eightBit.data[0] {
+		t.Errorf("Pixels with the same colour were different: %#v and %#v",
+			picture.data[0], eightBit.data[0])
+	}
+}
+
+func TestWrong16BitFormats(t *testing.T) {
+	for _, format := range []string{"RGB8", "RGBLE", "RGB16XE", "RGBA61", "RG16",
+		"RGBAA16", "16RGB", "RGB16LEBE"} {
+		if _, err := ParseImage([]byte{}, newHeader(format, 1, "None")); err == nil {
+			t.Errorf("Parsing the image did not return error for format %s", format)
+		}
+	}
+}
+
+func TestGrayscaleFormats(t *testing.T) {
+	picture, parseError := ParseImage([]byte{0, 77, 200}, newHeader("Y", 3, "None"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	pixel, _ := picture.InspectPixel(1, 0)
+
+	if err := assertColor(pixel, 77, 77, 77); err != nil {
+		t.Error(err)
+	}
+
+	picture, parseError = ParseImage([]byte{3, 127, 22, 1, 255, 100},
+		newHeader("AY", 2, "RLE"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	pixel, _ = picture.InspectPixel(0, 1)
+
+	if err := assertColor(pixel, 11, 11, 11); err != nil {
+		t.Error(err)
+	}
+
+	pixel, _ = picture.InspectPixel(1, 1)
+
+	if err := assertColor(pixel, 100, 100, 100); err != nil {
+		t.Error(err)
+	}
+
+	picture, parseError = ParseImage([]byte{0x12, 0x34}, newHeader("Y16LE", 1, "None"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	assertColor16(t, &picture.data[0], 0x3412, 0x3412, 0x3412, 0xffff)
+}
+
+func TestEncodeGrayscale(t *testing.T) {
+	data := []byte{
+		255, 0, 0, 0, 255, 0, 0, 0, 255, 90, 90, 90,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGB", 4, "None"))
+
+	encoded, err := Encode(picture, newHeader("Y", 4, "None"))
+
+	if err != nil {
+		t.Fatalf("Encoding returned error: %s", err)
+	}
+
+	if expected := []byte{76, 150, 29, 90}; !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+
+	gray, _ := ParseImage([]byte{10, 128, 200, 0}, newHeader("YA", 2, "None"))
+
+	encoded, _ = Encode(gray, newHeader("YA", 2, "RLE"))
+	again, _ := ParseImage(encoded, newHeader("YA", 2, "RLE"))
+
+	if !reflect.DeepEqual(gray.data, again.data) {
+		t.Errorf("Expected %v but got %v", gray.data, again.data)
+	}
+}
+
+func TestWrongGrayscaleFormats(t *testing.T) {
+	for _, format := range []string{"A", "YY", "YAA", "RGBY", "YR"} {
+		if _, err := ParseImage([]byte{}, newHeader(format, 1, "None")); err == nil {
+			t.Errorf("Parsing the image did not return error for format %s", format)
+		}
+	}
+}
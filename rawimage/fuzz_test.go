@@ -0,0 +1,70 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Small limits keep the fuzzer from spending its time allocating
+var fuzzLimits = Limits{MaxWidth: 1 << 10, MaxPixels: 1 << 16, MaxExpansion: 512}
+
+func FuzzParseImage(f *testing.F) {
+	f.Add([]byte{1, 2, 3, 4, 5, 6}, "RGB", uint(1), "None", uint(0), false, byte(0))
+	f.Add([]byte{3, 1, 2, 3}, "BGRA", uint(3), "RLE", uint(0), true, byte(1))
+	f.Add([]byte{0x81, 9, 0, 1}, "Y16LE", uint(2), "PackBits", uint(6), false, byte(0))
+	f.Add([]byte{255, 0}, "YA", uint(0), "IndexedRLE", uint(0), false, byte(0))
+	f.Add([]byte{1, 2, 3, 0, 4, 5, 6}, "RGB", uint(1), "None", uint(4), true, byte(0))
+	f.Add([]byte{1, 2, 5, 9, 4, 1, 2}, "YA", uint(1), "PredictRLE", uint(0), false,
+		byte(2))
+
+	f.Fuzz(func(t *testing.T, data []byte, format string, width uint,
+		encoding string, stride uint, bottomUp bool, layout byte) {
+
+		header := Header{Format: format, LineWidth: width, Encoding: encoding,
+			Palette: []Pixel{{Red: 1, Alpha: 255}}, Stride: stride,
+			BottomUp: bottomUp, Layout: (ChannelLayout)(layout)}
+
+		picture, err := ParseImageWithOptions(data, header,
+			Options{Limits: fuzzLimits})
+
+		if err != nil {
+			var imageError *ImageError
+			if !errors.As(err, &imageError) {
+				t.Fatalf("Error is not an ImageError: %v", err)
+			}
+			return
+		}
+
+		if len(picture.data)%(int)(width) != 0 {
+			t.Fatalf("%d pixels do not make rows of %d", len(picture.data), width)
+		}
+
+		if len(picture.data) > fuzzLimits.MaxPixels {
+			t.Fatalf("%d pixels are over the limit", len(picture.data))
+		}
+	})
+}
+
+func FuzzReadContainer(f *testing.F) {
+	var buffer bytes.Buffer
+	picture, _ := ParseImage([]byte{1, 2, 3, 4, 5, 6}, newHeader("Y", 3, "None"))
+	WriteContainer(&buffer, picture, newHeader("Y", 3, "RLE"))
+
+	f.Add(buffer.Bytes())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ReadContainer(bytes.NewReader(data))
+	})
+}
+
+func FuzzReadNetpbm(f *testing.F) {
+	f.Add([]byte("P6 1 1 255\n\x01\x02\x03"))
+	f.Add([]byte("P5 # comment\n2 1\n15\n\x0f\x05"))
+	f.Add([]byte("P7\nWIDTH 1\nHEIGHT 1\nDEPTH 2\nMAXVAL 255\n" +
+		"TUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n\x64\x00"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ReadNetpbm(bytes.NewReader(data))
+	})
+}
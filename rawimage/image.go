@@ -0,0 +1,110 @@
+package rawimage
+
+import (
+	"image"
+	"image/color"
+)
+
+// PixelModel converts any colour into a Pixel. It makes Image usable
+// everywhere the standard library expects an image.Image or a draw.Image.
+var PixelModel = color.ModelFunc(pixelModel)
+
+func pixelModel(c color.Color) color.Color {
+	if pixel, ok := c.(Pixel); ok {
+		return pixel
+	}
+
+	return pixelFromColor(c)
+}
+
+// color.Color wants the colours premultiplied which is exactly how we store
+// them, unless the image was parsed with StraightAlpha.
+func (pixel Pixel) RGBA() (r, g, b, a uint32) {
+	red, green, blue, alpha := pixel.RGBA64()
+	return (uint32)(red), (uint32)(green), (uint32)(blue), (uint32)(alpha)
+}
+
+// RGBA64 returns the premultiplied channels in full 16-bit precision. Pixels
+// from 8-bit formats are widened the same way the image/color package does.
+func (pixel Pixel) RGBA64() (r, g, b, a uint16) {
+	if pixel.deep && !pixel.straight {
+		return pixel.wide.red, pixel.wide.green, pixel.wide.blue, pixel.wide.alpha
+	}
+
+	if pixel.deep {
+		a = pixel.wide.alpha
+		return alphaBlend16(pixel.wide.red, a), alphaBlend16(pixel.wide.green, a),
+			alphaBlend16(pixel.wide.blue, a), a
+	}
+
+	red, green, blue, alpha := pixel.Premultiplied()
+	return widen(red), widen(green), widen(blue), widen(alpha)
+}
+
+func widen(channel byte) uint16 {
+	return (uint16)(channel) * 0x101
+}
+
+func pixelFromColor(c color.Color) Pixel {
+	r, g, b, a := c.RGBA()
+	return newPixel16((uint16)(r), (uint16)(g), (uint16)(b), (uint16)(a))
+}
+
+// NewImageFrom copies any image.Image into a new RGBA Image. The result
+// always starts at (0, 0) no matter where the bounds of src are.
+func NewImageFrom(src image.Image) *Image {
+	bounds := src.Bounds()
+
+	img := new(Image)
+	img.header = Header{Format: "RGBA", LineWidth: (uint)(bounds.Dx()),
+		Encoding: "None"}
+	img.data = make([]Pixel, 0, bounds.Dx()*bounds.Dy())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			img.data = append(img.data, pixelFromColor(src.At(x, y)))
+		}
+	}
+
+	return img
+}
+
+func (img *Image) ColorModel() color.Model {
+	return PixelModel
+}
+
+func (img *Image) Bounds() image.Rectangle {
+	return image.Rect(0, 0, img.width(), img.height())
+}
+
+// Pixels outside of the image are transparent as image.Image requires
+func (img *Image) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(img.Bounds())) {
+		return Pixel{}
+	}
+
+	return img.data[img.pixelIndex(x, y)]
+}
+
+// Set makes Image a draw.Image. Points outside of the image are ignored.
+func (img *Image) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(img.Bounds())) {
+		return
+	}
+
+	img.data[img.pixelIndex(x, y)] = pixelFromColor(c)
+}
+
+// Opaque lets image encoders skip the alpha channel when it is not needed.
+// The full 16-bit alpha is checked as the Alpha byte of deep pixels only holds
+// its high byte.
+func (img *Image) Opaque() bool {
+	for y := 0; y < img.height(); y++ {
+		for _, pixel := range img.row(y) {
+			if _, _, _, alpha := pixel.RGBA64(); alpha != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+package rawimage
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+var (
+	_ image.Image = (*Image)(nil)
+	_ draw.Image  = (*Image)(nil)
+	_ color.Color = Pixel{}
+)
+
+func TestImageBoundsAndAt(t *testing.T) {
+	data := []byte{
+		0, 12, 244, 127, 14, 26, 52, 127,
+		31, 33, 41, 255, 36, 133, 241, 255,
+		1, 2, 3, 0, 4, 5, 6, 255,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+
+	if bounds := picture.Bounds(); bounds != image.Rect(0, 0, 2, 3) {
+		t.Errorf("Wrong bounds: %v", bounds)
+	}
+
+	r, g, b, a := picture.At(1, 0).RGBA()
+
+	if r != 7*0x101 || g != 13*0x101 || b != 26*0x101 || a != 127*0x101 {
+		t.Errorf("Wrong colour at (1, 0): %d %d %d %d", r, g, b, a)
+	}
+
+	if _, _, _, a := picture.At(2, 0).RGBA(); a != 0 {
+		t.Error("Pixel outside of the image was not transparent")
+	}
+}
+
+func TestRGBImagesAreOpaque(t *testing.T) {
+	picture, _ := ParseImage([]byte{1, 2, 3}, newHeader("RGB", 1, "None"))
+
+	if !picture.Opaque() {
+		t.Error("Image without alpha channel was not opaque")
+	}
+
+	// The high byte of the alpha is 255 but the pixel is not opaque
+	deep, _ := ParseImage([]byte{0, 1, 0, 2, 0, 3, 0xff, 0x80},
+		newHeader("RGBA16", 1, "None"))
+
+	if deep.Opaque() {
+		t.Error("Image with 16-bit alpha 0xff80 was opaque")
+	}
+
+	deep, _ = ParseImage([]byte{0, 1, 0, 2, 0, 3, 0xff, 0xff},
+		newHeader("RGBA16", 1, "None"))
+
+	if !deep.Opaque() {
+		t.Error("Image with 16-bit alpha 0xffff was not opaque")
+	}
+}
+
+func TestImagePNGRoundTrip(t *testing.T) {
+	data := []byte{
+		0, 12, 244, 255, 14, 26, 52, 128,
+		31, 33, 41, 0, 36, 133, 241, 255,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+
+	var buffer bytes.Buffer
+
+	if err := png.Encode(&buffer, picture); err != nil {
+		t.Fatalf("Encoding to PNG returned error: %s", err)
+	}
+
+	decoded, err := png.Decode(&buffer)
+
+	if err != nil {
+		t.Fatalf("Decoding the PNG returned error: %s", err)
+	}
+
+	converted := NewImageFrom(decoded)
+
+	for index, pixel := range picture.data {
+		found := converted.data[index]
+		if pixel.Alpha != found.Alpha {
+			t.Errorf("Pixel %d: expected alpha %d but got %d", index, pixel.Alpha,
+				found.Alpha)
+		}
+		if err := assertColor(&found, pixel.Red, pixel.Green, pixel.Blue); err != nil {
+			t.Errorf("Pixel %d: %s", index, err)
+		}
+	}
+}
+
+func TestImageAsDrawDestination(t *testing.T) {
+	picture, _ := ParseImage(bytes.Repeat([]byte{0, 0, 0}, 4),
+		newHeader("RGB", 2, "None"))
+
+	red := image.NewUniform(color.RGBA{255, 0, 0, 255})
+	draw.Draw(picture, image.Rect(1, 0, 2, 2), red, image.Point{}, draw.Src)
+
+	pixel, _ := picture.InspectPixel(1, 1)
+
+	if err := assertColor(pixel, 255, 0, 0); err != nil {
+		t.Error(err)
+	}
+
+	pixel, _ = picture.InspectPixel(0, 1)
+
+	if err := assertColor(pixel, 0, 0, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewImageFromOffsetBounds(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(5, 5, 7, 6))
+	src.Set(6, 5, color.NRGBA{200, 100, 50, 255})
+
+	picture := NewImageFrom(src)
+
+	pixel, err := picture.InspectPixel(1, 0)
+
+	if err != nil {
+		t.Fatalf("Inspecting the pixel returned error: %s", err)
+	}
+
+	if err := assertColor(pixel, 200, 100, 50); err != nil {
+		t.Error(err)
+	}
+}
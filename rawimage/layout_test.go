@@ -0,0 +1,150 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseWithStride(t *testing.T) {
+	header := newHeader("Y", 3, "None")
+	header.Stride = 5
+
+	for _, data := range [][]byte{
+		{1, 2, 3, 0, 0, 4, 5, 6, 0, 0},
+		{1, 2, 3, 0, 0, 4, 5, 6, 0},
+		{1, 2, 3, 9, 9, 4, 5, 6},
+	} {
+		picture, err := ParseImage(data, header)
+
+		if err != nil {
+			t.Fatalf("Parsing %v returned error: %s", data, err)
+		}
+
+		assertNumbers(t, "stride", picture, [][]byte{{1, 2, 3}, {4, 5, 6}})
+
+		sequential, _ := parseSequential(data, header, Options{})
+
+		if !reflect.DeepEqual(picture, sequential) {
+			t.Errorf("Sequential parsing of %v differs", data)
+		}
+	}
+
+	// Half a row after the padding is still an error
+	_, err := ParseImage([]byte{1, 2, 3, 0, 0, 4}, header)
+
+	if !errors.Is(err, ErrPartialRow) {
+		t.Errorf("Expected a partial row error but got %v", err)
+	}
+}
+
+func TestParseBottomUp(t *testing.T) {
+	header := newHeader("Y", 3, "None")
+	header.BottomUp = true
+
+	for _, encoding := range []string{"None", "RLE"} {
+		header.Encoding = encoding
+		data := []byte{4, 5, 6, 1, 2, 3}
+
+		if encoding == "RLE" {
+			data = []byte{1, 4, 1, 5, 1, 6, 1, 1, 1, 2, 1, 3}
+		}
+
+		picture, err := ParseImage(data, header)
+
+		if err != nil {
+			t.Fatalf("Parsing with %s returned error: %s", encoding, err)
+		}
+
+		assertNumbers(t, "bottom-up "+encoding, picture,
+			[][]byte{{1, 2, 3}, {4, 5, 6}})
+
+		if pixel, _ := picture.InspectPixel(0, 1); pixel.Red != 4 {
+			t.Errorf("InspectPixel(0, 1) with %s gave %s", encoding, pixel)
+		}
+	}
+}
+
+func TestEncodeLayout(t *testing.T) {
+	picture := numberedImage(t)
+
+	header := newHeader("Y", 3, "None")
+	header.Stride, header.BottomUp = 4, true
+
+	encoded, err := Encode(picture, header)
+
+	if err != nil {
+		t.Fatalf("Encoding returned error: %s", err)
+	}
+
+	if expected := []byte{4, 5, 6, 0, 1, 2, 3, 0}; !bytes.Equal(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+
+	for _, encoding := range []string{"RLE", "PackBits"} {
+		header := newHeader("RGB", 3, encoding)
+		header.Stride, header.BottomUp = 12, true
+
+		encoded, err := Encode(picture, header)
+
+		if err != nil {
+			t.Fatalf("Encoding with %s returned error: %s", encoding, err)
+		}
+
+		decoded, _ := ParseImage(encoded, header)
+
+		assertNumbers(t, "round trip "+encoding, decoded,
+			[][]byte{{1, 2, 3}, {4, 5, 6}})
+	}
+
+	header = newHeader("Y", 3, "IndexedRLE")
+	header.Palette, header.BottomUp = Quantise(picture, 6), true
+
+	encoded, _ = Encode(picture, header)
+	decoded, _ := ParseImage(encoded, header)
+
+	assertNumbers(t, "round trip indexed", decoded, [][]byte{{1, 2, 3}, {4, 5, 6}})
+}
+
+func TestBadStride(t *testing.T) {
+	cases := []struct {
+		format, encoding string
+		stride           uint
+		expected         error
+	}{
+		{"RGB", "None", 5, ErrBadDimensions},
+		{"RGB", "RLE", 7, ErrBadDimensions},
+		{"Y", "Indexed", 4, ErrBadEncoding},
+	}
+
+	for _, test := range cases {
+		header := newHeader(test.format, 2, test.encoding)
+		header.Stride = test.stride
+		header.Palette = []Pixel{gray(1)}
+
+		if _, err := ParseImage(nil, header); !errors.Is(err, test.expected) {
+			t.Errorf("Stride %d of %s with %s: expected %v but got %v",
+				test.stride, test.format, test.encoding, test.expected, err)
+		}
+	}
+}
+
+func TestContainerIgnoresLayout(t *testing.T) {
+	header := newHeader("Y", 3, "None")
+	header.Stride, header.BottomUp = 8, true
+
+	var buffer bytes.Buffer
+
+	if err := WriteContainer(&buffer, numberedImage(t), header); err != nil {
+		t.Fatalf("Writing returned error: %s", err)
+	}
+
+	decoded, err := ReadContainer(&buffer)
+
+	if err != nil {
+		t.Fatalf("Reading returned error: %s", err)
+	}
+
+	assertNumbers(t, "container", decoded, [][]byte{{1, 2, 3}, {4, 5, 6}})
+}
@@ -0,0 +1,92 @@
+package rawimage
+
+import (
+	"math"
+)
+
+// Limits protect the parsers from payloads which would decode into enormous
+// images. A zero field takes its value from DefaultLimits and a negative one
+// turns that limit off.
+type Limits struct {
+	// The widest line, in pixels
+	MaxWidth int
+
+	// The most pixels a whole image may have. Decoder reads one row at a time
+	// so it is only checked by the functions which keep the whole image.
+	MaxPixels int
+
+	// How many times bigger than the encoded data the raw pixel bytes may
+	// be. The first expansionGrace bytes are not checked.
+	MaxExpansion float64
+}
+
+// DefaultLimits are used wherever no Limits are given. The expansion is well
+// above what the built-in encodings can reach with legitimate data.
+var DefaultLimits = Limits{
+	MaxWidth:     1 << 16,
+	MaxPixels:    1 << 26,
+	MaxExpansion: 4096,
+}
+
+// Small payloads can not do much harm however much they expand
+const expansionGrace = 1 << 16
+
+// Four channels of two bytes each
+const maxPixelSize = 8
+
+func (limits Limits) resolved() Limits {
+	resolve := func(value, fallback int) int {
+		if value == 0 {
+			value = fallback
+		}
+		if value < 0 {
+			return math.MaxInt
+		}
+		return value
+	}
+
+	result := Limits{
+		MaxWidth:     resolve(limits.MaxWidth, DefaultLimits.MaxWidth),
+		MaxPixels:    resolve(limits.MaxPixels, DefaultLimits.MaxPixels),
+		MaxExpansion: limits.MaxExpansion,
+	}
+
+	if result.MaxExpansion == 0 {
+		result.MaxExpansion = DefaultLimits.MaxExpansion
+	}
+	if result.MaxExpansion < 0 {
+		result.MaxExpansion = math.Inf(1)
+	}
+
+	return result
+}
+
+func (limits Limits) checkWidth(width uint) error {
+	if width > (uint)(limits.MaxWidth) {
+		return newImageError(LimitExceeded, "Line width is over the limit")
+	}
+	return nil
+}
+
+// The stride is limited as if all of it was pixels of the biggest format
+func (limits Limits) checkStride(stride uint) error {
+	if stride/maxPixelSize > (uint)(limits.MaxWidth) {
+		return newImageError(LimitExceeded, "Row stride is over the limit")
+	}
+	return nil
+}
+
+func (limits Limits) checkPixels(pixels int) error {
+	if pixels > limits.MaxPixels {
+		return newImageError(LimitExceeded, "Too many pixels")
+	}
+	return nil
+}
+
+func (limits Limits) checkExpansion(raw, encoded int64) error {
+	if raw > expansionGrace &&
+		(float64)(raw) > limits.MaxExpansion*(float64)(encoded) {
+		return newImageError(LimitExceeded, "Payload expands too much")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package rawimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestZeroLineWidth(t *testing.T) {
+	for _, data := range [][]byte{{}, {1, 2, 3}} {
+		_, err := ParseImage(data, newHeader("RGB", 0, "None"))
+
+		if !errors.Is(err, ErrBadDimensions) {
+			t.Errorf("Expected bad dimensions for %v but got %v", data, err)
+		}
+	}
+
+	if _, err := NewDecoder(nil, newHeader("RGB", 0, "RLE")); !errors.Is(err,
+		ErrBadDimensions) {
+		t.Errorf("Expected bad dimensions from the decoder but got %v", err)
+	}
+}
+
+func TestWidthAndPixelLimits(t *testing.T) {
+	data := make([]byte, 3*16)
+
+	_, err := ParseImageWithOptions(data, newHeader("RGB", 8, "None"),
+		Options{Limits: Limits{MaxWidth: 4}})
+
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected the width limit but got %v", err)
+	}
+
+	// The parallel and the sequential paths
+	for _, encoding := range []string{"None", "PackBits"} {
+		encoded, _ := Encode(mustParse(t, data, newHeader("RGB", 4, "None")),
+			newHeader("RGB", 4, encoding))
+
+		_, err = ParseImageWithOptions(encoded, newHeader("RGB", 4, encoding),
+			Options{Limits: Limits{MaxPixels: 15}})
+
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("Expected the pixel limit with %s but got %v", encoding, err)
+		}
+
+		_, err = ParseImageWithOptions(encoded, newHeader("RGB", 4, encoding),
+			Options{Limits: Limits{MaxPixels: 16}})
+
+		if err != nil {
+			t.Errorf("Image within the limit with %s returned error: %s",
+				encoding, err)
+		}
+	}
+
+	header := newHeader("RGB", 8, "None")
+	header.Stride = 1 << 40
+
+	if _, err := ParseImage(data, header); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected the stride limit but got %v", err)
+	}
+
+	// No limit at all
+	_, err = ParseImageWithOptions(make([]byte, 1<<17), newHeader("Y", 1<<17,
+		"None"), Options{Limits: Limits{MaxWidth: -1}})
+
+	if err != nil {
+		t.Errorf("Disabled width limit returned error: %s", err)
+	}
+}
+
+func TestExpansionLimit(t *testing.T) {
+	// Every two bytes make 255 pixels
+	var bomb []byte
+	for run := 0; run < 1000; run++ {
+		bomb = append(bomb, 255, 7)
+	}
+
+	header := newHeader("Y", 255, "RLE")
+
+	if _, err := ParseImage(bomb, header); err != nil {
+		t.Errorf("Default limits rejected a legitimate expansion: %s", err)
+	}
+
+	_, err := ParseImageWithOptions(bomb, header,
+		Options{Limits: Limits{MaxExpansion: 100}})
+
+	var imageError *ImageError
+
+	if !errors.As(err, &imageError) || imageError.Code != LimitExceeded {
+		t.Fatalf("Expected the expansion limit but got %v", err)
+	}
+
+	if imageError.Offset <= 0 || imageError.Offset >= (int64)(len(bomb)) {
+		t.Errorf("Expected the limit to stop decoding early, at byte %d",
+			imageError.Offset)
+	}
+}
+
+func TestContainerAndNetpbmLimits(t *testing.T) {
+	var container []byte
+	container = append(container, containerMagic...)
+	container = append(container, 1, 'Y', 4, 'N', 'o', 'n', 'e')
+	container = binary.BigEndian.AppendUint32(container, 1<<16)
+	container = binary.BigEndian.AppendUint32(container, 1<<16)
+
+	if _, err := ReadContainer(bytes.NewReader(container)); !errors.Is(err,
+		ErrLimitExceeded) {
+		t.Errorf("Expected the pixel limit for the container but got %v", err)
+	}
+
+	for _, header := range []string{
+		"P5 100000 1 255\n",
+		"P5 60000 9223372036854775807 255\n",
+	} {
+		if _, err := ReadNetpbm(strings.NewReader(header)); !errors.Is(err,
+			ErrLimitExceeded) {
+			t.Errorf("Expected a limit for %q but got %v", header, err)
+		}
+	}
+}
+
+func TestContainerAndNetpbmWithOptions(t *testing.T) {
+	picture := numberedImage(t)
+
+	var buffer bytes.Buffer
+	if err := WriteContainer(&buffer, picture, newHeader("Y", 3, "None")); err != nil {
+		t.Fatalf("Writing returned error: %s", err)
+	}
+	container := buffer.Bytes()
+
+	small := Options{Limits: Limits{MaxPixels: 5}}
+
+	if _, err := ReadContainerWithOptions(bytes.NewReader(container),
+		small); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected the pixel limit for the container but got %v", err)
+	}
+
+	if _, err := ReadNetpbmWithOptions(strings.NewReader("P5 3 2 255\n123456"),
+		small); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Expected the pixel limit for Netpbm but got %v", err)
+	}
+
+	// Without the width limit the header is fine and the samples are missing
+	unlimited := Options{Limits: Limits{MaxWidth: -1, MaxPixels: -1}}
+
+	if _, err := ReadNetpbmWithOptions(strings.NewReader("P5 100000 1 255\n"),
+		unlimited); !errors.Is(err, ErrBadContainer) {
+		t.Errorf("Expected missing samples but got %v", err)
+	}
+
+	decoded, err := ReadContainerWithOptions(bytes.NewReader(container), unlimited)
+
+	if err != nil {
+		t.Fatalf("Reading returned error: %s", err)
+	}
+
+	assertNumbers(t, "unlimited container", decoded, [][]byte{{1, 2, 3}, {4, 5, 6}})
+}
+
+func mustParse(t *testing.T, data []byte, header Header) *Image {
+	picture, err := ParseImage(data, header)
+
+	if err != nil {
+		t.Fatalf("Parsing returned error: %s", err)
+	}
+
+	return picture
+}
@@ -0,0 +1,329 @@
+package rawimage
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Binary Netpbm images: PGM (P5), PPM (P6) and PAM (P7). PPM and PGM have no
+// alpha so they get the premultiplied colour, which is the image on top of
+// black. PAM keeps alpha and, as the Netpbm documentation says, stores the
+// straight colour next to it.
+//
+// Images with 16-bit pixels are written with a maxval of 65535, all others
+// with 255.
+
+func init() {
+	image.RegisterFormat("pgm", "P5", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("ppm", "P6", decodeNetpbm, decodeNetpbmConfig)
+	image.RegisterFormat("pam", "P7", decodeNetpbm, decodeNetpbmConfig)
+}
+
+// WritePPM writes img as a binary PPM
+func WritePPM(writer io.Writer, img *Image) error {
+	format, maxValue := img.netpbmFormat("RGB")
+
+	header := fmt.Sprintf("P6\n%d %d\n%d\n", img.width(), img.height(), maxValue)
+
+	return writeNetpbm(writer, img, header, format)
+}
+
+// WritePAM writes img as a PAM with the RGB_ALPHA tuple type
+func WritePAM(writer io.Writer, img *Image) error {
+	format, maxValue := img.netpbmFormat("RGBA")
+
+	header := fmt.Sprintf(
+		"P7\nWIDTH %d\nHEIGHT %d\nDEPTH 4\nMAXVAL %d\nTUPLTYPE RGB_ALPHA\nENDHDR\n",
+		img.width(), img.height(), maxValue)
+
+	return writeNetpbm(writer, img, header, format)
+}
+
+func (img *Image) netpbmFormat(channels string) (string, int) {
+	for y := 0; y < img.height(); y++ {
+		for _, pixel := range img.row(y) {
+			if pixel.deep {
+				return channels + "16", 0xffff
+			}
+		}
+	}
+	return channels, 0xff
+}
+
+func writeNetpbm(writer io.Writer, img *Image, header, format string) error {
+	samples, err := Encode(img, Header{Format: format,
+		LineWidth: img.header.LineWidth, Encoding: "None"})
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(writer, header); err != nil {
+		return err
+	}
+
+	_, err = writer.Write(samples)
+
+	return err
+}
+
+type netpbmHeader struct {
+	width, height int
+	maxValue      int
+	format        string
+}
+
+// ReadNetpbm reads a binary PGM, PPM or PAM image. PAM images have to be of one
+// of the GRAYSCALE, GRAYSCALE_ALPHA, RGB or RGB_ALPHA tuple types. Samples with
+// a maxval other than 255 or 65535 are scaled to the closest of the two.
+func ReadNetpbm(reader io.Reader) (*Image, error) {
+	return ReadNetpbmWithOptions(reader, Options{})
+}
+
+// ReadNetpbmWithOptions is ReadNetpbm with the parsing options and limits of
+// ParseImageWithOptions.
+func ReadNetpbmWithOptions(reader io.Reader, options Options) (*Image, error) {
+	buffered := bufio.NewReader(reader)
+	header, err := readNetpbmHeader(buffered)
+
+	if err != nil {
+		return nil, err
+	}
+
+	limits := options.Limits.resolved()
+
+	if err := limits.checkWidth((uint)(header.width)); err != nil {
+		return nil, err
+	}
+
+	// Divided so that the product of two big numbers cannot overflow, even
+	// with the limit turned off
+	maxPixels := min(limits.MaxPixels, math.MaxInt/maxPixelSize)
+	if header.height > maxPixels/header.width {
+		return nil, newImageError(LimitExceeded, "Too many pixels")
+	}
+
+	format, _ := parseFormat(header.format)
+	samples := make([]byte, header.width*header.height*format.pixelSize())
+
+	if _, err := io.ReadFull(buffered, samples); err != nil {
+		return nil, newImageError(BadContainer, "Not enough Netpbm samples")
+	}
+
+	if header.maxValue != 0xff && header.maxValue != 0xffff {
+		scaleSamples(samples, header.maxValue, format.depth)
+	}
+
+	return ParseImageWithOptions(samples, Header{Format: header.format,
+		LineWidth: (uint)(header.width), Encoding: "None"}, options)
+}
+
+func readNetpbmHeader(reader *bufio.Reader) (*netpbmHeader, error) {
+	magic, err := netpbmToken(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	header := new(netpbmHeader)
+	channels := ""
+
+	switch magic {
+	case "P5", "P6":
+		var values [3]int
+		for index := range values {
+			if values[index], err = netpbmNumber(reader); err != nil {
+				return nil, err
+			}
+		}
+
+		header.width, header.height, header.maxValue = values[0], values[1],
+			values[2]
+
+		channels = "Y"
+		if magic == "P6" {
+			channels = "RGB"
+		}
+
+		// A single whitespace separates the header from the samples
+		if _, err := reader.ReadByte(); err != nil {
+			return nil, newImageError(BadContainer, "Truncated Netpbm header")
+		}
+	case "P7":
+		if channels, err = readPAMHeader(reader, header); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, newImageError(BadContainer, "Not a PGM, PPM or PAM image")
+	}
+
+	if header.width <= 0 || header.height <= 0 {
+		return nil, newImageError(BadDimensions, "Netpbm images cannot be empty")
+	}
+
+	if header.maxValue <= 0 || header.maxValue > 0xffff {
+		return nil, newImageError(BadContainer, "Netpbm maxval out of range")
+	}
+
+	header.format = channels
+	if header.maxValue > 0xff {
+		header.format += "16"
+	}
+
+	return header, nil
+}
+
+func readPAMHeader(reader *bufio.Reader, header *netpbmHeader) (string, error) {
+	depth, tupleType := 0, ""
+
+	for {
+		key, err := netpbmToken(reader)
+
+		if err != nil {
+			return "", err
+		}
+
+		if key == "ENDHDR" {
+			// The rest of the ENDHDR line
+			if _, err := reader.ReadString('\n'); err != nil {
+				return "", newImageError(BadContainer, "Truncated Netpbm header")
+			}
+			break
+		}
+
+		var value *int
+
+		switch key {
+		case "WIDTH":
+			value = &header.width
+		case "HEIGHT":
+			value = &header.height
+		case "DEPTH":
+			value = &depth
+		case "MAXVAL":
+			value = &header.maxValue
+		case "TUPLTYPE":
+			if tupleType, err = netpbmToken(reader); err != nil {
+				return "", err
+			}
+			continue
+		default:
+			return "", newImageError(BadContainer, "Unknown PAM header "+key)
+		}
+
+		if *value, err = netpbmNumber(reader); err != nil {
+			return "", err
+		}
+	}
+
+	channels := map[string]string{
+		"GRAYSCALE":       "Y",
+		"GRAYSCALE_ALPHA": "YA",
+		"RGB":             "RGB",
+		"RGB_ALPHA":       "RGBA",
+	}[tupleType]
+
+	if len(channels) == 0 || len(channels) != depth {
+		return "", newImageError(BadContainer,
+			"Unsupported PAM tuple type "+tupleType)
+	}
+
+	return channels, nil
+}
+
+// The next whitespace separated token, skipping comments
+func netpbmToken(reader *bufio.Reader) (string, error) {
+	var token []byte
+
+	for {
+		char, err := reader.ReadByte()
+
+		if err != nil {
+			if len(token) > 0 && err == io.EOF {
+				return string(token), nil
+			}
+			return "", newImageError(BadContainer, "Truncated Netpbm header")
+		}
+
+		switch {
+		case char == '#' && len(token) == 0:
+			if _, err := reader.ReadString('\n'); err != nil {
+				return "", newImageError(BadContainer, "Truncated Netpbm header")
+			}
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			if len(token) > 0 {
+				// The whitespace after the last header value belongs to
+				// the samples separator, so it is put back
+				reader.UnreadByte()
+				return string(token), nil
+			}
+		default:
+			token = append(token, char)
+		}
+	}
+}
+
+func netpbmNumber(reader *bufio.Reader) (int, error) {
+	token, err := netpbmToken(reader)
+
+	if err != nil {
+		return 0, err
+	}
+
+	number, err := strconv.Atoi(token)
+
+	if err != nil {
+		return 0, newImageError(BadContainer, "Bad Netpbm number "+token)
+	}
+
+	return number, nil
+}
+
+// Stretches samples from 0 to maxValue over the whole range of depth bytes
+func scaleSamples(samples []byte, maxValue, depth int) {
+	limit := (1 << (8 * depth)) - 1
+
+	for offset := 0; offset < len(samples); offset += depth {
+		value := (int)(samples[offset])
+		if depth == 2 {
+			value = value<<8 | (int)(samples[offset+1])
+		}
+
+		value = (min(value, maxValue)*limit + maxValue/2) / maxValue
+
+		if depth == 2 {
+			samples[offset] = (byte)(value >> 8)
+			samples[offset+1] = (byte)(value)
+		} else {
+			samples[offset] = (byte)(value)
+		}
+	}
+}
+
+func decodeNetpbm(reader io.Reader) (image.Image, error) {
+	img, err := ReadNetpbm(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+func decodeNetpbmConfig(reader io.Reader) (image.Config, error) {
+	header, err := readNetpbmHeader(bufio.NewReader(reader))
+
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{
+		ColorModel: PixelModel,
+		Width:      header.width,
+		Height:     header.height,
+	}, nil
+}
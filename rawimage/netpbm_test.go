@@ -0,0 +1,128 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestPPMRoundTrip(t *testing.T) {
+	picture := numberedImage(t)
+
+	var buffer bytes.Buffer
+
+	if err := WritePPM(&buffer, picture); err != nil {
+		t.Fatalf("Writing returned error: %s", err)
+	}
+
+	if !strings.HasPrefix(buffer.String(), "P6\n3 2\n255\n") {
+		t.Errorf("Wrong PPM header: %q", buffer.String()[:11])
+	}
+
+	decoded, err := ReadNetpbm(&buffer)
+
+	if err != nil {
+		t.Fatalf("Reading returned error: %s", err)
+	}
+
+	assertNumbers(t, "PPM", decoded, [][]byte{{1, 2, 3}, {4, 5, 6}})
+}
+
+func TestPAMRoundTrip(t *testing.T) {
+	data := []byte{200, 100, 50, 128, 0x12, 0x34, 0x56, 0x78}
+	picture, _ := ParseImage(data, newHeader("RGBA", 1, "None"))
+
+	for _, current := range []*Image{picture, picture.FlipVertical()} {
+		var buffer bytes.Buffer
+
+		if err := WritePAM(&buffer, current); err != nil {
+			t.Fatalf("Writing returned error: %s", err)
+		}
+
+		decoded, err := ReadNetpbm(&buffer)
+
+		if err != nil {
+			t.Fatalf("Reading returned error: %s", err)
+		}
+
+		for index := range current.data {
+			if premultipliedOf(&decoded.data[index]) !=
+				premultipliedOf(&current.data[index]) {
+				t.Errorf("Pixel %d differs: %s and %s", index,
+					decoded.data[index], current.data[index])
+			}
+		}
+	}
+}
+
+func TestPAMKeepsSixteenBits(t *testing.T) {
+	data := []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc}
+	picture, _ := ParseImage(data, newHeader("RGB16", 1, "None"))
+
+	var buffer bytes.Buffer
+	WritePAM(&buffer, picture)
+
+	if !strings.Contains(buffer.String(), "MAXVAL 65535\n") {
+		t.Errorf("16-bit image written without a 16-bit maxval")
+	}
+
+	decoded, _ := ReadNetpbm(&buffer)
+
+	if decoded.data[0] != picture.data[0] {
+		t.Errorf("Expected %#v but got %#v", picture.data[0], decoded.data[0])
+	}
+}
+
+func TestReadNetpbm(t *testing.T) {
+	// Comments, odd whitespace and a maxval of 15
+	pgm := "P5 # gray\n2 # wide\n1\n15\n\x0f\x05"
+
+	picture, err := ReadNetpbm(strings.NewReader(pgm))
+
+	if err != nil {
+		t.Fatalf("Reading the PGM returned error: %s", err)
+	}
+
+	assertNumbers(t, "PGM", picture, [][]byte{{255, 85}})
+
+	pam := "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 2\nMAXVAL 255\n" +
+		"TUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n\x64\x00"
+
+	picture, err = ReadNetpbm(strings.NewReader(pam))
+
+	if err != nil {
+		t.Fatalf("Reading the PAM returned error: %s", err)
+	}
+
+	if _, _, _, alpha := picture.At(0, 0).RGBA(); alpha != 0 {
+		t.Errorf("Expected a transparent pixel but alpha is %d", alpha)
+	}
+
+	// Registered with the image package
+	decoded, name, err := image.Decode(strings.NewReader("P6 1 1 255\n\x01\x02\x03"))
+
+	if err != nil || name != "ppm" || decoded.Bounds().Dx() != 1 {
+		t.Errorf("image.Decode returned %q and error %v", name, err)
+	}
+}
+
+func TestReadNetpbmErrors(t *testing.T) {
+	for _, broken := range []string{
+		"P3 1 1 255\n1 2 3",
+		"P6 1 1 255\n\x01\x02",
+		"P6 0 1 255\n",
+		"P6 1 1 70000\n\x01\x02\x03",
+		"P7\nWIDTH 1\nHEIGHT 1\nDEPTH 3\nMAXVAL 255\nTUPLTYPE CMYK\nENDHDR\n",
+		"P6 1",
+	} {
+		_, err := ReadNetpbm(strings.NewReader(broken))
+
+		var imageError *ImageError
+
+		if !errors.As(err, &imageError) {
+			t.Errorf("Expected an image error for %q but got %v", broken, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package rawimage
+
+import (
+	"bytes"
+	"io"
+)
+
+// PackBits works like the TIFF and Apple PackBits but counts whole pixels
+// instead of bytes. Every run starts with a signed header byte n:
+//
+//	0 to 127     n + 1 literal pixels follow
+//	-1 to -127   the next pixel is repeated 1 - n times
+//	-128         nothing, the next byte is a header again
+//
+// Unlike RLE, pixels which do not repeat cost a single header byte for up to
+// 128 of them.
+
+// Both kinds of runs are at most this long
+const maxPackBitsRun = 128
+
+func init() {
+	registerBuiltin("PackBits", pixelDecoder(newPackBitsReader),
+		pixelEncoder(encodePackBits))
+}
+
+type packBitsReader struct {
+	reader  byteReader
+	pixel   []byte
+	pending []byte // what is left to be emitted from the current pixel
+	literal int    // literal pixels left in the current run
+	repeat  int    // how many more times the current pixel has to be emitted
+}
+
+func newPackBitsReader(reader io.Reader, pixelSize int) io.Reader {
+	return &packBitsReader{reader: asByteReader(reader),
+		pixel: make([]byte, pixelSize)}
+}
+
+func (packBits *packBitsReader) Read(buffer []byte) (int, error) {
+	read := 0
+
+	for read < len(buffer) {
+		if len(packBits.pending) > 0 {
+			copied := copy(buffer[read:], packBits.pending)
+			read += copied
+			packBits.pending = packBits.pending[copied:]
+			continue
+		}
+
+		if err := packBits.nextPixel(); err != nil {
+			if read > 0 && err == io.EOF {
+				return read, nil
+			}
+			return read, err
+		}
+	}
+
+	return read, nil
+}
+
+func (packBits *packBitsReader) nextPixel() error {
+	for packBits.repeat == 0 && packBits.literal == 0 {
+		header, err := packBits.reader.ReadByte()
+
+		if err != nil {
+			return err
+		}
+
+		switch count := (int)((int8)(header)); {
+		case count >= 0:
+			packBits.literal = count + 1
+		case count > -128:
+			if err := packBits.readPixel(); err != nil {
+				return err
+			}
+			packBits.repeat = 1 - count
+		}
+	}
+
+	if packBits.repeat > 0 {
+		packBits.repeat--
+	} else {
+		packBits.literal--
+		if err := packBits.readPixel(); err != nil {
+			return err
+		}
+	}
+
+	packBits.pending = packBits.pixel
+
+	return nil
+}
+
+func (packBits *packBitsReader) readPixel() error {
+	if _, err := io.ReadFull(packBits.reader, packBits.pixel); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return newImageError(TruncatedPixel, "Not enough data for pixel")
+		}
+		return err
+	}
+	return nil
+}
+
+func encodePackBits(raw []byte, pixelSize int) []byte {
+	var out []byte
+
+	pixelsCount := len(raw) / pixelSize
+	pixelAt := func(index int) []byte {
+		return raw[index*pixelSize : (index+1)*pixelSize]
+	}
+
+	literalStart := 0
+
+	flushLiteral := func(end int) {
+		if end > literalStart {
+			out = append(out, (byte)(end-literalStart-1))
+			out = append(out, raw[literalStart*pixelSize:end*pixelSize]...)
+		}
+	}
+
+	for index := 0; index < pixelsCount; {
+		run := 1
+
+		for run < maxPackBitsRun && index+run < pixelsCount &&
+			bytes.Equal(pixelAt(index), pixelAt(index+run)) {
+			run++
+		}
+
+		if run == 1 {
+			index++
+			if index-literalStart == maxPackBitsRun {
+				flushLiteral(index)
+				literalStart = index
+			}
+			continue
+		}
+
+		flushLiteral(index)
+
+		out = append(out, (byte)((int8)(1-run)))
+		out = append(out, pixelAt(index)...)
+
+		index += run
+		literalStart = index
+	}
+
+	flushLiteral(pixelsCount)
+
+	return out
+}
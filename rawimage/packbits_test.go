@@ -0,0 +1,89 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPackBitsFormat(t *testing.T) {
+	header := newHeader("RGB", 5, "PackBits")
+
+	data := []byte{
+		0x80,                // no-op
+		1, 1, 2, 3, 4, 5, 6, // two literal pixels
+		0xfe, 7, 8, 9, // repeated three times
+	}
+
+	picture, parseError := ParseImage(data, header)
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	expected := [][]byte{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}, {7, 8, 9}, {7, 8, 9}}
+
+	for index, rgb := range expected {
+		if err := assertColor(&picture.data[index], rgb...); err != nil {
+			t.Errorf("Pixel %d: %s", index, err)
+		}
+	}
+}
+
+func TestPackBitsTruncated(t *testing.T) {
+	header := newHeader("RGB", 1, "PackBits")
+
+	for _, data := range [][]byte{{1, 1, 2, 3}, {0xff, 1, 2}, {0}} {
+		_, parseError := ParseImage(data, header)
+
+		if !errors.Is(parseError, ErrTruncatedPixel) {
+			t.Errorf("Expected error for %v but got %v", data, parseError)
+		}
+	}
+}
+
+func TestPackBitsEncoder(t *testing.T) {
+	raw := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 7, 8, 9, 7, 8, 9}
+
+	encoded := encodePackBits(raw, 3)
+	expected := []byte{1, 1, 2, 3, 4, 5, 6, 0xfe, 7, 8, 9}
+
+	if !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+}
+
+func TestPackBitsRoundTripIsSmallerThanRLE(t *testing.T) {
+	var data []byte
+
+	// Noise with a long flat area in the middle, more than one run worth
+	for index := 0; index < 300; index++ {
+		data = append(data, byte(index), byte(index*7), byte(index*13))
+	}
+	data = append(data, bytes.Repeat([]byte{9, 9, 9}, 300)...)
+
+	picture, _ := ParseImage(data, newHeader("RGB", 600, "None"))
+
+	packed, err := Encode(picture, newHeader("BGR", 600, "PackBits"))
+
+	if err != nil {
+		t.Fatalf("Encoding returned error: %s", err)
+	}
+
+	rle, _ := Encode(picture, newHeader("BGR", 600, "RLE"))
+
+	if len(packed) >= len(rle) {
+		t.Errorf("PackBits took %d bytes, RLE only %d", len(packed), len(rle))
+	}
+
+	decoded, parseError := ParseImage(packed, newHeader("BGR", 600, "PackBits"))
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	if !reflect.DeepEqual(picture.data, decoded.data) {
+		t.Error("The decoded image was different from the encoded one")
+	}
+}
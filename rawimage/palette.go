@@ -0,0 +1,284 @@
+package rawimage
+
+import (
+	"io"
+	"sort"
+)
+
+// Indexed encodings store a single byte per pixel which is an index into
+// Header.Palette. With "IndexedRLE" the index bytes are run-length encoded the
+// same way "RLE" encodes pixels, one count byte followed by one index.
+//
+// The palette is turned into the header format before decoding, so the pixels
+// come out exactly as if their palette colours were parsed from raw data.
+func init() {
+	registerBuiltin("Indexed", func(reader io.Reader, _ int,
+		header Header) io.Reader {
+
+		return newPaletteReader(reader, header)
+	}, encodeIndexes)
+
+	registerBuiltin("IndexedRLE", func(reader io.Reader, _ int,
+		header Header) io.Reader {
+
+		return newPaletteReader(newRLEReader(reader, 1), header)
+	}, func(raw []byte, pixelSize int, header Header) []byte {
+		return encodeRLE(encodeIndexes(raw, pixelSize, header), 1)
+	})
+}
+
+// An index is a single byte so there can be no more colours than this
+const maxPaletteSize = 256
+
+func isIndexed(encoding string) bool {
+	return encoding == "Indexed" || encoding == "IndexedRLE"
+}
+
+func isPaletteValid(header Header) error {
+	if len(header.Palette) == 0 || len(header.Palette) > maxPaletteSize {
+		return newImageError(BadEncoding,
+			"Indexed encodings need a palette of 1 to 256 colours")
+	}
+	return nil
+}
+
+// The palette colours of header as raw bytes in the header format
+func paletteColours(header Header) [][]byte {
+	format, _ := parseFormat(header.Format)
+
+	colours := make([][]byte, len(header.Palette))
+	for index := range header.Palette {
+		colours[index] = make([]byte, format.pixelSize())
+		format.encode(&header.Palette[index], colours[index])
+	}
+
+	return colours
+}
+
+// Reads index bytes and emits the palette colours they point to, already in
+// the header format.
+type paletteReader struct {
+	reader  byteReader
+	colours [][]byte
+	pending []byte // what is left to be emitted from the current colour
+}
+
+func newPaletteReader(indexes io.Reader, header Header) io.Reader {
+	return &paletteReader{reader: asByteReader(indexes),
+		colours: paletteColours(header)}
+}
+
+func (palette *paletteReader) Read(buffer []byte) (int, error) {
+	read := 0
+
+	for read < len(buffer) {
+		if len(palette.pending) == 0 {
+			index, err := palette.reader.ReadByte()
+
+			if err != nil {
+				if read > 0 && err == io.EOF {
+					return read, nil
+				}
+				return read, err
+			}
+
+			if (int)(index) >= len(palette.colours) {
+				return read, newImageError(BadEncoding,
+					"Palette index out of range")
+			}
+
+			palette.pending = palette.colours[index]
+		}
+
+		copied := copy(buffer[read:], palette.pending)
+		read += copied
+		palette.pending = palette.pending[copied:]
+	}
+
+	return read, nil
+}
+
+// Every raw pixel is replaced by the index of the palette colour which is the
+// closest to it once both are parsed in the header format
+func encodeIndexes(raw []byte, pixelSize int, header Header) []byte {
+	format, _ := parseFormat(header.Format)
+
+	colours := paletteColours(header)
+	palette := make([][4]byte, len(colours))
+	for index := range colours {
+		parsed := format.decode(colours[index], Options{})
+		palette[index] = premultipliedOf(&parsed)
+	}
+
+	indexes := make([]byte, 0, len(raw)/pixelSize)
+	closest := make(map[string]byte)
+
+	for start := 0; start+pixelSize <= len(raw); start += pixelSize {
+		pixel := raw[start : start+pixelSize]
+
+		index, ok := closest[string(pixel)]
+		if !ok {
+			parsed := format.decode(pixel, Options{})
+			index = nearestColour(premultipliedOf(&parsed), palette)
+			closest[string(pixel)] = index
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	return indexes
+}
+
+func nearestColour(colour [4]byte, palette [][4]byte) byte {
+	best, bestDistance := 0, -1
+
+	for index, candidate := range palette {
+		distance := 0
+
+		for channel := range colour {
+			difference := (int)(colour[channel]) - (int)(candidate[channel])
+			distance += difference * difference
+		}
+
+		if bestDistance < 0 || distance < bestDistance {
+			best, bestDistance = index, distance
+		}
+	}
+
+	return (byte)(best)
+}
+
+func premultipliedOf(pixel *Pixel) [4]byte {
+	red, green, blue, alpha := pixel.Premultiplied()
+	return [4]byte{red, green, blue, alpha}
+}
+
+// Quantise finds at most colours colours which represent img well, using
+// median cut over the premultiplied channels. The result is meant to be used
+// as Header.Palette for the indexed encodings.
+func Quantise(img *Image, colours int) []Pixel {
+	colours = max(1, min(colours, maxPaletteSize))
+
+	counts := make(map[[4]byte]int)
+	for y := 0; y < img.height(); y++ {
+		row := img.row(y)
+		for x := range row {
+			counts[premultipliedOf(&row[x])]++
+		}
+	}
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	all := make(colourBox, 0, len(counts))
+	for colour, count := range counts {
+		all = append(all, countedColour{colour, count})
+	}
+
+	// Map iteration is random. Sorting makes the palette the same every time.
+	sort.Slice(all, func(i, j int) bool {
+		return lessColour(all[i].colour, all[j].colour)
+	})
+
+	boxes := []colourBox{all}
+
+	for len(boxes) < colours {
+		widest, widestRange, channel := -1, 0, 0
+
+		for index, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if boxChannel, boxRange := box.widestChannel(); boxRange > widestRange {
+				widest, widestRange, channel = index, boxRange, boxChannel
+			}
+		}
+
+		if widest < 0 {
+			break
+		}
+
+		low, high := boxes[widest].split(channel)
+		boxes[widest] = low
+		boxes = append(boxes, high)
+	}
+
+	palette := make([]Pixel, len(boxes))
+	for index, box := range boxes {
+		palette[index] = box.average()
+	}
+
+	return palette
+}
+
+type countedColour struct {
+	colour [4]byte
+	count  int
+}
+
+type colourBox []countedColour
+
+func (box colourBox) widestChannel() (channel, width int) {
+	for candidate := 0; candidate < 4; candidate++ {
+		low, high := 255, 0
+		for _, counted := range box {
+			low = min(low, (int)(counted.colour[candidate]))
+			high = max(high, (int)(counted.colour[candidate]))
+		}
+
+		if high-low > width {
+			channel, width = candidate, high-low
+		}
+	}
+	return
+}
+
+// Splits the box in two along channel so that both halves cover about the
+// same number of pixels. Neither half is ever empty.
+func (box colourBox) split(channel int) (colourBox, colourBox) {
+	sort.SliceStable(box, func(i, j int) bool {
+		return box[i].colour[channel] < box[j].colour[channel]
+	})
+
+	total := 0
+	for _, counted := range box {
+		total += counted.count
+	}
+
+	median, seen := 1, box[0].count
+	for median < len(box)-1 && seen*2 < total {
+		seen += box[median].count
+		median++
+	}
+
+	return box[:median:median], box[median:]
+}
+
+func (box colourBox) average() Pixel {
+	var sums [4]int
+	total := 0
+
+	for _, counted := range box {
+		for channel := range sums {
+			sums[channel] += (int)(counted.colour[channel]) * counted.count
+		}
+		total += counted.count
+	}
+
+	for channel := range sums {
+		sums[channel] = (sums[channel] + total/2) / total
+	}
+
+	return Pixel{Red: (byte)(sums[0]), Green: (byte)(sums[1]),
+		Blue: (byte)(sums[2]), Alpha: (byte)(sums[3])}
+}
+
+func lessColour(a, b [4]byte) bool {
+	for channel := range a {
+		if a[channel] != b[channel] {
+			return a[channel] < b[channel]
+		}
+	}
+	return false
+}
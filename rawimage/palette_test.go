@@ -0,0 +1,124 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestParseIndexedImage(t *testing.T) {
+	header := newHeader("BGRA", 2, "Indexed")
+	header.Palette = []Pixel{rgba(0, 0, 0, 255), rgba(128, 0, 0, 128)}
+
+	picture, err := ParseImage([]byte{1, 0, 0, 1}, header)
+
+	if err != nil {
+		t.Fatalf("Parsing returned error: %s", err)
+	}
+
+	assertChannels(t, "first", premultipliedOf(&picture.data[0]), 128, 0, 0, 128)
+	assertChannels(t, "second", premultipliedOf(&picture.data[1]), 0, 0, 0, 255)
+	assertChannels(t, "fourth", premultipliedOf(&picture.data[3]), 128, 0, 0, 128)
+
+	// The same image with run-length encoded indexes
+	header.Encoding = "IndexedRLE"
+	rle, err := ParseImage([]byte{1, 1, 2, 0, 1, 1}, header)
+
+	if err != nil {
+		t.Fatalf("Parsing IndexedRLE returned error: %s", err)
+	}
+
+	for index := range picture.data {
+		if rle.data[index] != picture.data[index] {
+			t.Errorf("Pixel %d differs: %s and %s", index, rle.data[index],
+				picture.data[index])
+		}
+	}
+}
+
+func TestIndexedPaletteFollowsFormat(t *testing.T) {
+	header := newHeader("Y", 1, "Indexed")
+	header.Palette = []Pixel{rgba(255, 0, 0, 255)}
+
+	picture, _ := ParseImage([]byte{0}, header)
+
+	// Gray formats keep only the luminance of the palette colours
+	assertChannels(t, "gray", premultipliedOf(&picture.data[0]), 76, 76, 76, 255)
+}
+
+func TestIndexedErrors(t *testing.T) {
+	header := newHeader("RGB", 1, "Indexed")
+
+	if _, err := ParseImage([]byte{0}, header); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("Expected a bad encoding error without palette but got %v", err)
+	}
+
+	header.Palette = []Pixel{rgba(1, 2, 3, 255)}
+
+	_, err := ParseImage([]byte{0, 1}, header)
+
+	var imageError *ImageError
+
+	if !errors.As(err, &imageError) || imageError.Code != BadEncoding ||
+		imageError.Y != 1 {
+		t.Errorf("Expected an out of palette error on the second row but got %v",
+			err)
+	}
+}
+
+func TestQuantise(t *testing.T) {
+	data := []byte{
+		250, 0, 0, 0, 0, 250, 0, 255, 0, 255, 0, 0,
+		250, 0, 0, 0, 0, 250, 0, 255, 0, 255, 0, 0,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGB", 4, "None"))
+	palette := Quantise(picture, 3)
+
+	if len(palette) != 3 {
+		t.Fatalf("Expected 3 colours but got %d", len(palette))
+	}
+
+	header := newHeader("RGB", 4, "IndexedRLE")
+	header.Palette = palette
+
+	encoded, err := Encode(picture, header)
+
+	if err != nil {
+		t.Fatalf("Encoding returned error: %s", err)
+	}
+
+	decoded, _ := ParseImage(encoded, header)
+
+	// The reds are merged but the blues and the greens are kept apart
+	assertChannels(t, "red", premultipliedOf(&decoded.data[0]), 253, 0, 0, 255)
+	assertChannels(t, "red", premultipliedOf(&decoded.data[3]), 253, 0, 0, 255)
+	assertChannels(t, "blue", premultipliedOf(&decoded.data[1]), 0, 0, 250, 255)
+	assertChannels(t, "green", premultipliedOf(&decoded.data[2]), 0, 255, 0, 255)
+
+	if many := Quantise(picture, 256); len(many) != 4 {
+		t.Errorf("Expected a colour for each of the 4 colours but got %d",
+			len(many))
+	}
+}
+
+func TestIndexedContainer(t *testing.T) {
+	picture, _ := ParseImage([]byte{1, 2, 3, 4, 5, 6}, newHeader("Y", 3, "None"))
+
+	header := newHeader("Y", 3, "Indexed")
+	header.Palette = Quantise(picture, 6)
+
+	var buffer bytes.Buffer
+
+	if err := WriteContainer(&buffer, picture, header); err != nil {
+		t.Fatalf("Writing returned error: %s", err)
+	}
+
+	decoded, err := ReadContainer(&buffer)
+
+	if err != nil {
+		t.Fatalf("Reading returned error: %s", err)
+	}
+
+	assertNumbers(t, "indexed container", decoded, [][]byte{{1, 2, 3}, {4, 5, 6}})
+}
@@ -0,0 +1,100 @@
+package rawimage
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Images with fewer pixels than this are decoded in a single goroutine. Below
+// it starting the workers costs more than they save.
+const parallelThreshold = 1 << 16
+
+// Whether data can be decoded by decodeParallel. That is only the case for
+// raw payloads which hold whole rows and nothing else. Everything else goes
+// through the Decoder which knows where the data went wrong. "None" is a
+// built-in encoding so it cannot be registered with a decoder of its own.
+func canDecodeParallel(data []byte, header Header, format *pixelFormat) bool {
+	if header.Encoding != "None" || header.Layout != Interleaved {
+		return false
+	}
+
+	if len(data) == 0 {
+		return true
+	}
+
+	rows := parallelRows(data, header, format)
+	if rows == 0 {
+		return false
+	}
+
+	// Like the Decoder, accept the padding of the last row in full, in part
+	// or not at all
+	rowBytes, stride := header.rowLayout(format.pixelSize())
+	rest := len(data) - (rows-1)*stride - rowBytes
+
+	return rest <= stride-rowBytes
+}
+
+// How many rows have all of their pixels in data
+func parallelRows(data []byte, header Header, format *pixelFormat) int {
+	rowBytes, stride := header.rowLayout(format.pixelSize())
+	return (len(data) + stride - rowBytes) / stride
+}
+
+// Decodes a raw payload straight into a preallocated slice. The rows are split
+// evenly between as many goroutines as there are processors to run them.
+func decodeParallel(data []byte, rows int, header Header, format *pixelFormat,
+	options Options) []Pixel {
+
+	width := (int)(header.LineWidth)
+	pixels := make([]Pixel, rows*width)
+
+	decodeRows := func(first, last int) {
+		rowBytes, stride := header.rowLayout(format.pixelSize())
+
+		for y := first; y < last; y++ {
+			target := y
+			if header.BottomUp {
+				target = rows - 1 - y
+			}
+
+			decodePixels(data[y*stride:y*stride+rowBytes],
+				pixels[target*width:(target+1)*width], format, options)
+		}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if len(pixels) < parallelThreshold || workers < 2 {
+		decodeRows(0, rows)
+		return pixels
+	}
+
+	rowsPerWorker := (rows + workers - 1) / workers
+
+	var group sync.WaitGroup
+
+	for first := 0; first < rows; first += rowsPerWorker {
+		last := min(first+rowsPerWorker, rows)
+
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			decodeRows(first, last)
+		}()
+	}
+
+	group.Wait()
+
+	return pixels
+}
+
+func decodePixels(data []byte, pixels []Pixel, format *pixelFormat,
+	options Options) {
+
+	pixelSize := format.pixelSize()
+
+	for index := range pixels {
+		offset := index * pixelSize
+		pixels[index] = format.decode(data[offset:offset+pixelSize], options)
+	}
+}
@@ -0,0 +1,111 @@
+package rawimage
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func randomPayload(pixels, pixelSize int) []byte {
+	data := make([]byte, pixels*pixelSize)
+	rand.New(rand.NewSource(42)).Read(data)
+	return data
+}
+
+func TestParallelDecodingMatchesSequential(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	// Not a multiple of the number of workers on purpose
+	const width, height = 301, 307
+
+	for _, format := range []string{"RGBA", "BGR", "YA", "RGBA16LE", "Y16"} {
+		for _, options := range []Options{{}, {Alpha: BothAlpha},
+			{Blend: LegacyBlend}} {
+
+			header := newHeader(format, width, "None")
+			parsed, _ := parseFormat(format)
+			data := randomPayload(width*height, parsed.pixelSize())
+
+			parallel, err := ParseImageWithOptions(data, header, options)
+			if err != nil {
+				t.Fatalf("Parsing %s returned error: %s", format, err)
+			}
+
+			sequential, _ := parseSequential(data, header, options)
+
+			if !reflect.DeepEqual(parallel, sequential) {
+				t.Errorf("Parallel decoding of %s with %+v differs", format, options)
+			}
+		}
+	}
+}
+
+func TestParallelDecodingLeavesErrorsToDecoder(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	header := newHeader("RGB", 300, "None")
+	data := randomPayload(300*300, 3)
+
+	for _, broken := range [][]byte{data[:len(data)-1], data[:len(data)-3]} {
+		_, parallelErr := ParseImage(broken, header)
+		_, sequentialErr := parseSequential(broken, header, Options{})
+
+		var parallel, sequential *ImageError
+
+		if !errors.As(parallelErr, &parallel) || !errors.As(sequentialErr, &sequential) {
+			t.Fatalf("Expected image errors but got %v and %v", parallelErr,
+				sequentialErr)
+		}
+
+		if *parallel != *sequential {
+			t.Errorf("Expected %#v but got %#v", sequential, parallel)
+		}
+	}
+}
+
+func TestParallelDecodingUsesRegisteredNone(t *testing.T) {
+	calls := 0
+
+	RegisterEncoding("None", func(reader io.Reader, _ int, _ Header) io.Reader {
+		calls++
+		return reader
+	})
+
+	header := newHeader("RGB", 2, "None")
+
+	// Well formed payloads go the parallel way, partial rows the sequential
+	// one. Both have to decode the same "None".
+	ParseImage([]byte{1, 2, 3, 4, 5, 6}, header)
+	ParseImage([]byte{1, 2, 3, 4}, header)
+
+	if calls != 0 {
+		t.Errorf("A replacement of None was called %d times", calls)
+	}
+}
+
+func benchmarkParse(b *testing.B, parse func([]byte, Header, Options) (*Image, error)) {
+	const width, height = 2048, 2048
+
+	header := newHeader("RGBA", width, "None")
+	data := randomPayload(width*height, 4)
+
+	b.SetBytes((int64)(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := parse(data, header, Options{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseSequential(b *testing.B) {
+	benchmarkParse(b, parseSequential)
+}
+
+func BenchmarkParseParallel(b *testing.B) {
+	benchmarkParse(b, ParseImageWithOptions)
+}
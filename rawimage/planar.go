@@ -0,0 +1,149 @@
+package rawimage
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+)
+
+// ChannelLayout is the way the channels of the pixels are arranged in the raw
+// data, that is after the encoding has been undone.
+type ChannelLayout int
+
+const (
+	// Every pixel has all of its channels next to each other, RGBRGB...
+	Interleaved ChannelLayout = iota
+
+	// Every channel has a plane of its own and the planes follow each other
+	// in the order of the format. RGB is all the reds, then all the greens
+	// and then all the blues.
+	Planar
+
+	// The colour channels stay interleaved but alpha is moved to a plane of
+	// its own after them. Formats without alpha are the same as Interleaved.
+	SemiPlanar
+)
+
+// The channels in each of the planes of the layout, by their position in the
+// format
+func (layout ChannelLayout) planes(format *pixelFormat) [][]int {
+	var planes [][]int
+	alpha := strings.IndexByte(format.channels, 'A')
+
+	switch layout {
+	case Planar:
+		for channel := range format.channels {
+			planes = append(planes, []int{channel})
+		}
+	case SemiPlanar:
+		var colour []int
+		for channel := range format.channels {
+			if channel != alpha {
+				colour = append(colour, channel)
+			}
+		}
+
+		planes = append(planes, colour)
+		if alpha >= 0 {
+			planes = append(planes, []int{alpha})
+		}
+	default:
+		var all []int
+		for channel := range format.channels {
+			all = append(all, channel)
+		}
+		planes = append(planes, all)
+	}
+
+	return planes
+}
+
+func isLayoutValid(header Header) error {
+	switch header.Layout {
+	case Interleaved:
+		return nil
+	case Planar, SemiPlanar:
+	default:
+		return newImageError(BadFormat, "Unknown channel layout")
+	}
+
+	if header.Stride != 0 {
+		return newImageError(BadFormat, "Row stride is only for interleaved data")
+	}
+
+	if isIndexed(header.Encoding) {
+		return newImageError(BadEncoding,
+			"Indexed encodings are always interleaved")
+	}
+
+	return nil
+}
+
+// Moves every sample between the two layouts. pixels has to be whole in both.
+func rearrange(source []byte, format *pixelFormat, layout ChannelLayout,
+	toInterleaved bool) []byte {
+
+	depth, pixelSize := format.depth, format.pixelSize()
+	pixels := len(source) / pixelSize
+	target := make([]byte, len(source))
+	planeStart := 0
+
+	for _, plane := range layout.planes(format) {
+		for pixel := 0; pixel < pixels; pixel++ {
+			for index, channel := range plane {
+				planar := planeStart + (pixel*len(plane)+index)*depth
+				interleaved := pixel*pixelSize + channel*depth
+
+				if toInterleaved {
+					copy(target[interleaved:interleaved+depth],
+						source[planar:planar+depth])
+				} else {
+					copy(target[planar:planar+depth],
+						source[interleaved:interleaved+depth])
+				}
+			}
+		}
+
+		planeStart += pixels * len(plane) * depth
+	}
+
+	return target
+}
+
+// Planes can not be read one row at a time, so the whole raw stream is read
+// and interleaved before the first pixel is decoded. The raw data is limited
+// to the size of the biggest image the limits allow.
+func (decoder *Decoder) readPlanes() error {
+	decoder.planesRead = true
+
+	limit := decoder.limits.MaxPixels
+	if limit < math.MaxInt/decoder.pixelSize {
+		limit *= decoder.pixelSize
+	}
+	limit = min(limit, math.MaxInt-1)
+
+	data, err := io.ReadAll(io.LimitReader(decoder.raw, (int64)(limit)+1))
+
+	if err != nil {
+		return decoder.locate(err)
+	}
+
+	if len(data) > limit {
+		return decoder.locate(newImageError(LimitExceeded, "Too many pixels"))
+	}
+
+	// The data ends in the middle of a pixel. There is no telling which of the
+	// planes are short, so the error is for the first incomplete pixel just
+	// like with interleaved data.
+	if len(data)%decoder.pixelSize != 0 {
+		decoder.pixels = len(data) / decoder.pixelSize
+		return decoder.locate(newImageError(TruncatedPixel,
+			"Not enough data in the planes for pixel"))
+	}
+
+	decoder.raw = bytes.NewReader(rearrange(data, decoder.format,
+		decoder.header.Layout, true))
+
+	return nil
+}
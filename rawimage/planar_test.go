@@ -0,0 +1,167 @@
+package rawimage
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePlanar(t *testing.T) {
+	interleaved, _ := ParseImage([]byte{
+		1, 2, 3, 255, 4, 5, 6, 128,
+		7, 8, 9, 0, 10, 11, 12, 64,
+	}, newHeader("RGBA", 2, "None"))
+
+	cases := []struct {
+		layout ChannelLayout
+		data   []byte
+	}{
+		{Planar, []byte{
+			1, 4, 7, 10, 2, 5, 8, 11, 3, 6, 9, 12, 255, 128, 0, 64,
+		}},
+		{SemiPlanar, []byte{
+			1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 255, 128, 0, 64,
+		}},
+	}
+
+	for _, test := range cases {
+		header := newHeader("RGBA", 2, "None")
+		header.Layout = test.layout
+
+		picture, err := ParseImage(test.data, header)
+
+		if err != nil {
+			t.Fatalf("Layout %d returned error: %s", test.layout, err)
+		}
+
+		if !reflect.DeepEqual(picture.data, interleaved.data) {
+			t.Errorf("Layout %d gave %v", test.layout, picture.data)
+		}
+	}
+
+	// Without alpha semi-planar is the same as interleaved
+	header := newHeader("BGR", 1, "None")
+	header.Layout = SemiPlanar
+
+	picture, _ := ParseImage([]byte{3, 2, 1}, header)
+	assertChannels(t, "semi-planar BGR", premultipliedOf(&picture.data[0]),
+		1, 2, 3, 255)
+}
+
+func TestPlanarRoundTrip(t *testing.T) {
+	data := []byte{
+		0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0,
+		0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88,
+	}
+	picture, _ := ParseImage(data, newHeader("ARGB16LE", 1, "None"))
+
+	for _, encoding := range []string{"None", "RLE", "PackBits"} {
+		for _, layout := range []ChannelLayout{Planar, SemiPlanar} {
+			header := newHeader("ARGB16LE", 1, encoding)
+			header.Layout, header.BottomUp = layout, true
+
+			encoded, err := Encode(picture, header)
+
+			if err != nil {
+				t.Fatalf("Encoding returned error: %s", err)
+			}
+
+			decoded, err := ParseImage(encoded, header)
+
+			if err != nil {
+				t.Fatalf("Parsing %s layout %d returned error: %s", encoding,
+					layout, err)
+			}
+
+			if !reflect.DeepEqual(decoded.data, picture.data) {
+				t.Errorf("Round trip of %s layout %d differs", encoding, layout)
+			}
+		}
+	}
+
+	header := newHeader("Y", 3, "None")
+	header.Layout = Planar
+
+	// A single channel has a single plane
+	encoded, _ := Encode(numberedImage(t), header)
+
+	if expected := []byte{1, 2, 3, 4, 5, 6}; !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+}
+
+func TestShortPlanes(t *testing.T) {
+	header := newHeader("RGB", 1, "None")
+	header.Layout = Planar
+
+	// The second pixel has a red and a green but no blue
+	_, err := ParseImage([]byte{1, 2, 3, 4, 5}, header)
+
+	var imageError *ImageError
+
+	if !errors.As(err, &imageError) || imageError.Code != TruncatedPixel {
+		t.Fatalf("Expected a truncated pixel error but got %v", err)
+	}
+
+	if imageError.X != 0 || imageError.Y != 1 || imageError.Offset != 5 {
+		t.Errorf("Wrong position: (%d, %d) at byte %d", imageError.X,
+			imageError.Y, imageError.Offset)
+	}
+
+	// Whole pixels but not whole rows
+	header.LineWidth = 2
+
+	if _, err := ParseImage(make([]byte, 9), header); !errors.Is(err,
+		ErrPartialRow) {
+		t.Errorf("Expected a partial row error but got %v", err)
+	}
+}
+
+func TestBadLayout(t *testing.T) {
+	cases := []struct {
+		header   Header
+		expected error
+	}{
+		{Header{Format: "RGB", LineWidth: 1, Encoding: "None", Layout: 3},
+			ErrBadFormat},
+		{Header{Format: "RGB", LineWidth: 1, Encoding: "None", Layout: Planar,
+			Stride: 4}, ErrBadFormat},
+		{Header{Format: "RGB", LineWidth: 1, Encoding: "Indexed",
+			Layout: Planar, Palette: []Pixel{gray(1)}}, ErrBadEncoding},
+	}
+
+	for _, test := range cases {
+		if _, err := ParseImage(nil, test.header); !errors.Is(err, test.expected) {
+			t.Errorf("%+v: expected %v but got %v", test.header, test.expected, err)
+		}
+	}
+}
+
+func TestPlanarContainerRoundTrip(t *testing.T) {
+	picture, _ := ParseImage([]byte{
+		1, 2, 3, 255, 4, 5, 6, 128,
+	}, newHeader("RGBA", 2, "None"))
+
+	for _, layout := range []ChannelLayout{Planar, SemiPlanar} {
+		header := newHeader("RGBA", 2, "None")
+		header.Layout = layout
+
+		var buffer bytes.Buffer
+
+		if err := WriteContainer(&buffer, picture, header); err != nil {
+			t.Fatalf("Writing returned error: %s", err)
+		}
+
+		decoded, err := ReadContainer(&buffer)
+
+		if err != nil {
+			t.Fatalf("Reading layout %d returned error: %s", layout, err)
+		}
+
+		if !reflect.DeepEqual(decoded.data, picture.data) {
+			t.Errorf("Container round trip of layout %d gave %v", layout,
+				decoded.data)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package rawimage
+
+import (
+	"io"
+)
+
+// The predictor encodings filter every row of raw data the way PNG does. A
+// row starts with a byte for its filter and continues with the filtered
+// bytes:
+//
+//	0 None     the bytes as they are
+//	1 Sub      the difference with the same byte of the pixel on the left
+//	2 Up       the difference with the same byte of the row above
+//	3 Average  the difference with the average of the left and up bytes
+//	4 Paeth    the difference with the Paeth predictor of left, up and
+//	           up-left
+//
+// Rows are Header.Stride bytes long, padding included, and the left neighbour
+// of a byte is a whole pixel before it.
+//
+// With "PredictRLE" the filtered bytes of every row are run-length encoded
+// the same way "RLE" encodes pixels. Runs do not cross the end of a row. Even
+// gradients become mostly runs once filtered.
+func init() {
+	registerBuiltin("Predict", predictDecoder(false), predictEncoder(false))
+	registerBuiltin("PredictRLE", predictDecoder(true), predictEncoder(true))
+}
+
+// One of the PNG filters
+type rowFilter byte
+
+const (
+	filterNone rowFilter = iota
+	filterSub
+	filterUp
+	filterAverage
+	filterPaeth
+)
+
+func predictDecoder(runLength bool) EncodingDecoder {
+	return func(reader io.Reader, pixelSize int, header Header) io.Reader {
+		_, stride := header.rowLayout(pixelSize)
+		return newPredictReader(reader, pixelSize, stride, runLength)
+	}
+}
+
+func predictEncoder(runLength bool) EncodingEncoder {
+	return func(raw []byte, pixelSize int, header Header) []byte {
+		_, stride := header.rowLayout(pixelSize)
+		return filterRows(raw, stride, pixelSize, runLength)
+	}
+}
+
+// The value filter predicts for the byte at index of row. The bytes before
+// index and the whole of previous are already unfiltered.
+func (filter rowFilter) predict(row, previous []byte, index,
+	pixelSize int) byte {
+
+	var left, up, upLeft byte
+
+	if index >= pixelSize {
+		left = row[index-pixelSize]
+		upLeft = previous[index-pixelSize]
+	}
+	up = previous[index]
+
+	switch filter {
+	case filterSub:
+		return left
+	case filterUp:
+		return up
+	case filterAverage:
+		return (byte)(((int)(left) + (int)(up)) / 2)
+	case filterPaeth:
+		return paeth(left, up, upLeft)
+	}
+
+	return 0
+}
+
+func paeth(left, up, upLeft byte) byte {
+	estimate := (int)(left) + (int)(up) - (int)(upLeft)
+	distanceLeft := abs(estimate - (int)(left))
+	distanceUp := abs(estimate - (int)(up))
+	distanceUpLeft := abs(estimate - (int)(upLeft))
+
+	switch {
+	case distanceLeft <= distanceUp && distanceLeft <= distanceUpLeft:
+		return left
+	case distanceUp <= distanceUpLeft:
+		return up
+	}
+	return upLeft
+}
+
+func abs(value int) int {
+	return max(value, -value)
+}
+
+// Filters every row of raw with the filter which gives the smallest sum of
+// absolute values, taking the bytes as signed. It is the heuristic the PNG
+// specification recommends and small values compress best. With runLength
+// the filtered rows are run-length encoded.
+func filterRows(raw []byte, rowBytes, pixelSize int, runLength bool) []byte {
+	out := make([]byte, 0, len(raw)+len(raw)/rowBytes+1)
+	previous := make([]byte, rowBytes)
+	candidate := make([]byte, rowBytes)
+	best := make([]byte, rowBytes)
+
+	for start := 0; start < len(raw); start += rowBytes {
+		row := raw[start:min(start+rowBytes, len(raw))]
+		bestFilter, bestSum := filterNone, -1
+
+		for filter := filterNone; filter <= filterPaeth; filter++ {
+			sum := 0
+			for index := range row {
+				value := row[index] - filter.predict(row, previous, index, pixelSize)
+				candidate[index] = value
+				sum += abs((int)((int8)(value)))
+			}
+
+			if bestSum < 0 || sum < bestSum {
+				bestFilter, bestSum = filter, sum
+				best, candidate = candidate, best
+			}
+		}
+
+		out = append(out, (byte)(bestFilter))
+
+		if runLength {
+			out = append(out, encodeRLE(best[:len(row)], pixelSize)...)
+		} else {
+			out = append(out, best[:len(row)]...)
+		}
+
+		copy(previous, row)
+	}
+
+	return out
+}
+
+// Undoes filterRows one row at a time
+type predictReader struct {
+	reader    byteReader
+	runs      *rleReader // reads the rows when they are run-length encoded
+	pixelSize int
+	previous  []byte
+	row       []byte
+	pending   []byte // what is left to be emitted from the current row
+}
+
+func newPredictReader(reader io.Reader, pixelSize, rowBytes int,
+	runLength bool) io.Reader {
+
+	predict := &predictReader{
+		reader:    asByteReader(reader),
+		pixelSize: pixelSize,
+		previous:  make([]byte, rowBytes),
+		row:       make([]byte, rowBytes),
+	}
+
+	if runLength {
+		predict.runs = newRLEReader(predict.reader, pixelSize).(*rleReader)
+	}
+
+	return predict
+}
+
+func (predict *predictReader) Read(buffer []byte) (int, error) {
+	read := 0
+
+	for read < len(buffer) {
+		if len(predict.pending) == 0 {
+			if err := predict.nextRow(); err != nil {
+				if read > 0 && err == io.EOF {
+					return read, nil
+				}
+				return read, err
+			}
+		}
+
+		copied := copy(buffer[read:], predict.pending)
+		read += copied
+		predict.pending = predict.pending[copied:]
+	}
+
+	return read, nil
+}
+
+func (predict *predictReader) nextRow() error {
+	filterByte, err := predict.reader.ReadByte()
+
+	if err != nil {
+		return err
+	}
+
+	filter := (rowFilter)(filterByte)
+
+	if filter > filterPaeth {
+		return newImageError(BadEncoding, "Unknown row filter")
+	}
+
+	// The last row may be cut short. Whatever there is of it is returned so
+	// that the decoder can tell where exactly the data ends.
+	var rowReader io.Reader = predict.reader
+	if predict.runs != nil {
+		rowReader = predict.runs
+	}
+
+	length, err := io.ReadFull(rowReader, predict.row)
+
+	if predict.runs != nil && predict.runs.remaining > 0 {
+		return newImageError(BadEncoding, "Run crosses the end of a row")
+	}
+
+	switch {
+	case err == io.EOF:
+		return newImageError(TruncatedPixel, "Not enough data for pixel")
+	case err != nil && err != io.ErrUnexpectedEOF:
+		return err
+	}
+
+	row := predict.row[:length]
+	for index := range row {
+		row[index] += filter.predict(row, predict.previous, index,
+			predict.pixelSize)
+	}
+
+	copy(predict.previous, row)
+	predict.pending = row
+
+	return nil
+}
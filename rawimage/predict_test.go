@@ -0,0 +1,150 @@
+package rawimage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePredicted(t *testing.T) {
+	data := []byte{
+		1, 10, 1, 1, // Sub
+		2, 1, 1, 1, // Up
+		3, 6, 1, 1, // Average
+		4, 1, 1, 1, // Paeth
+		0, 1, 2, 3, // None
+	}
+
+	picture, err := ParseImage(data, newHeader("Y", 3, "Predict"))
+
+	if err != nil {
+		t.Fatalf("Parsing returned error: %s", err)
+	}
+
+	assertNumbers(t, "predicted", picture, [][]byte{
+		{10, 11, 12},
+		{11, 12, 13},
+		{11, 12, 13},
+		{12, 13, 14},
+		{1, 2, 3},
+	})
+}
+
+func TestPaeth(t *testing.T) {
+	cases := []struct{ left, up, upLeft, expected byte }{
+		{10, 20, 10, 20},
+		{10, 20, 20, 10},
+		{10, 20, 15, 15},
+		{100, 0, 255, 0},
+		{0, 0, 0, 0},
+	}
+
+	for _, test := range cases {
+		if found := paeth(test.left, test.up, test.upLeft); found != test.expected {
+			t.Errorf("Paeth of %d, %d, %d: expected %d but got %d", test.left,
+				test.up, test.upLeft, test.expected, found)
+		}
+	}
+}
+
+func gradient(t *testing.T, width, height int) *Image {
+	data := make([]byte, 0, width*height*3)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			data = append(data, (byte)(x*2), (byte)(y*3), (byte)(x+y))
+		}
+	}
+
+	return mustParse(t, data, newHeader("RGB", (uint)(width), "None"))
+}
+
+func TestPredictorsCompressGradients(t *testing.T) {
+	picture := gradient(t, 64, 64)
+
+	rle, _ := Encode(picture, newHeader("RGB", 64, "RLE"))
+	predicted, _ := Encode(picture, newHeader("RGB", 64, "PredictRLE"))
+
+	if len(predicted)*4 > len(rle) {
+		t.Errorf("Predictors do not help: %d bytes against %d with RLE",
+			len(predicted), len(rle))
+	}
+
+	// The first row has nothing above it but every next one is the one above
+	// plus a constant, which is best predicted from it
+	filtered := filterRows(make([]byte, 6), 3, 3, false)
+	if filtered[0] != (byte)(filterNone) || filtered[4] != (byte)(filterNone) {
+		t.Errorf("Flat rows should not be filtered: %v", filtered)
+	}
+
+	raw := []byte{1, 2, 3, 4, 5, 6, 11, 12, 13, 14, 15, 16}
+	filtered = filterRows(raw, 6, 3, false)
+
+	if filtered[0] != (byte)(filterSub) || filtered[7] != (byte)(filterPaeth) {
+		t.Errorf("Unexpected filters %d and %d", filtered[0], filtered[7])
+	}
+}
+
+func TestPredictedRoundTrip(t *testing.T) {
+	picture := gradient(t, 7, 5)
+
+	headers := []Header{
+		newHeader("RGB", 7, "Predict"),
+		newHeader("RGB", 7, "PredictRLE"),
+		{Format: "RGB", LineWidth: 7, Encoding: "Predict", Stride: 23},
+		{Format: "RGB", LineWidth: 7, Encoding: "PredictRLE", BottomUp: true},
+		{Format: "RGB", LineWidth: 7, Encoding: "PredictRLE", Layout: Planar},
+	}
+
+	for _, header := range headers {
+		encoded, err := Encode(picture, header)
+
+		if err != nil {
+			t.Fatalf("Encoding with %+v returned error: %s", header, err)
+		}
+
+		decoded, err := ParseImage(encoded, header)
+
+		if err != nil {
+			t.Fatalf("Parsing with %+v returned error: %s", header, err)
+		}
+
+		if !reflect.DeepEqual(decoded.data, picture.data) {
+			t.Errorf("Round trip with %+v differs", header)
+		}
+	}
+}
+
+func TestPredictedErrors(t *testing.T) {
+	header := newHeader("Y", 2, "Predict")
+
+	_, err := ParseImage([]byte{0, 1, 2, 9, 1, 2}, header)
+
+	var imageError *ImageError
+
+	if !errors.As(err, &imageError) || imageError.Code != BadEncoding ||
+		imageError.Y != 1 {
+		t.Errorf("Expected an unknown filter error on the second row but got %v",
+			err)
+	}
+
+	_, err = ParseImage([]byte{0, 3, 5}, newHeader("Y", 2, "PredictRLE"))
+
+	if !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("Expected a run crossing the row to fail but got %v", err)
+	}
+
+	cases := []struct {
+		data     []byte
+		expected error
+	}{
+		{[]byte{0, 1, 2, 1, 5}, ErrPartialRow},
+		{[]byte{0, 1, 2, 1}, ErrTruncatedPixel},
+	}
+
+	for _, test := range cases {
+		if _, err := ParseImage(test.data, header); !errors.Is(err, test.expected) {
+			t.Errorf("%v: expected %v but got %v", test.data, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Package rawimage parses raw pixel dumps described by a small Header. It
+// started as the solution of challenge3 and homework2 and both of them are
+// now built on top of it.
+package rawimage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+type Header struct {
+	Format    string
+	LineWidth uint
+	Encoding  string
+
+	// Colours of the indexed encodings, every other encoding ignores it.
+	// Payload index i stands for Palette[i].
+	Palette []Pixel
+
+	// Bytes from the start of a row to the start of the next one in the raw
+	// pixel data. Whatever follows the pixels of a row is padding and is
+	// skipped. Zero means the rows are packed without any padding.
+	Stride uint
+
+	// The rows go from the bottom of the image up, as in BMP files. Images
+	// are always top-down once parsed.
+	BottomUp bool
+
+	// How the channels are arranged in the raw pixel data
+	Layout ChannelLayout
+}
+
+// Bytes of the pixels of a row and from the start of one row to the next
+func (header Header) rowLayout(pixelSize int) (rowBytes, stride int) {
+	rowBytes = (int)(header.LineWidth) * pixelSize
+	return rowBytes, max(rowBytes, (int)(header.Stride))
+}
+
+type Pixel struct {
+	Red              byte
+	Green            byte
+	Blue             byte
+	Alpha            byte
+	needsPremultiply bool
+
+	// Pixels from 16-bit formats keep their full precision in wide. The byte
+	// fields above are always kept in sync with its high bytes.
+	deep bool
+	wide channels16
+
+	// The colour fields hold straight instead of premultiplied colour
+	straight bool
+
+	// Parsing with BothAlpha keeps the straight colour next to the
+	// premultiplied one so that neither has to be approximated.
+	keepsStraight bool
+	straightWide  channels16
+}
+
+type channels16 struct {
+	red, green, blue, alpha uint16
+}
+
+// In case *someone* calls us with a wrong method name
+// I will penalise him/her with one extra copy
+func (pixel *Pixel) Color() Pixel {
+	return pixel.Colour()
+}
+
+// quack quack!
+func (pixel *Pixel) Colour() Pixel {
+	return *pixel
+}
+
+func (pixel *Pixel) premultiply(mode BlendMode) {
+	if !pixel.needsPremultiply {
+		return
+	}
+
+	pixel.needsPremultiply = false
+
+	pixel.Red = mode.blend(pixel.Red, pixel.Alpha)
+	pixel.Green = mode.blend(pixel.Green, pixel.Alpha)
+	pixel.Blue = mode.blend(pixel.Blue, pixel.Alpha)
+
+}
+
+func (pixel Pixel) String() string {
+	return fmt.Sprintf("Red: %d, Green: %d, Blue: %d", pixel.Red, pixel.Green,
+		pixel.Blue)
+}
+
+type Image struct {
+	header Header
+	data   []Pixel
+
+	// Pixels between the starts of two rows in data. Only sub images have it
+	// different from the width, zero means it is the same.
+	stride int
+}
+
+func (img *Image) InspectPixel(x uint, y uint) (*Pixel, error) {
+	if err := img.checkBounds(x, y); err != nil {
+		return nil, err
+	}
+
+	return &img.data[img.pixelIndex((int)(x), (int)(y))], nil
+}
+
+func (img *Image) width() int {
+	return (int)(img.header.LineWidth)
+}
+
+// Sub images share data with their parent, so data ends right after the last
+// pixel of the last row and everything after it belongs to the parent.
+func (img *Image) height() int {
+	if len(img.data) == 0 || img.width() == 0 {
+		return 0
+	}
+	return (len(img.data)-img.width())/img.rowStride() + 1
+}
+
+func (img *Image) rowStride() int {
+	if img.stride == 0 {
+		return img.width()
+	}
+	return img.stride
+}
+
+func (img *Image) pixelIndex(x, y int) int {
+	return y*img.rowStride() + x
+}
+
+func (img *Image) row(y int) []Pixel {
+	start := img.pixelIndex(0, y)
+	return img.data[start : start+img.width()]
+}
+
+func (img *Image) checkBounds(x uint, y uint) error {
+	if x >= (uint)(img.width()) || y >= (uint)(img.height()) {
+		err := newImageError(OutOfRange, "Index out of range")
+		err.X, err.Y = (int)(x), (int)(y)
+		return err
+	}
+	return nil
+}
+
+func isHeaderValid(header Header) error {
+	if header.LineWidth == 0 {
+		return newImageError(BadDimensions, "Header line width cannot be zero")
+	}
+
+	format, err := parseFormat(header.Format)
+
+	if err != nil {
+		return err
+	}
+
+	if err := isStrideValid(header, format.pixelSize()); err != nil {
+		return err
+	}
+
+	if err := isLayoutValid(header); err != nil {
+		return err
+	}
+
+	if isIndexed(header.Encoding) {
+		if err := isPaletteValid(header); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := lookupEncoding(header.Encoding); !ok {
+		return newImageError(BadEncoding, "Wrong header encoding "+header.Encoding)
+	}
+
+	return nil
+}
+
+func isStrideValid(header Header, pixelSize int) error {
+	if header.Stride == 0 {
+		return nil
+	}
+
+	// Divided so that a huge width cannot overflow
+	if header.Stride/(uint)(pixelSize) < header.LineWidth {
+		return newImageError(BadDimensions, "Row stride is shorter than a row")
+	}
+
+	if isIndexed(header.Encoding) {
+		return newImageError(BadEncoding,
+			"Indexed encodings cannot have a row stride")
+	}
+
+	// Other encodings count whole pixels so they cannot hold partial ones.
+	// Predict alone works with single bytes.
+	if header.Encoding != "None" && header.Encoding != "Predict" &&
+		header.Stride%(uint)(pixelSize) != 0 {
+		return newImageError(BadDimensions,
+			"Row stride of encoded data has to be whole pixels")
+	}
+
+	return nil
+}
+
+func ParseImage(data []byte, header Header) (*Image, error) {
+	return ParseImageWithOptions(data, header, Options{})
+}
+
+func ParseImageWithOptions(data []byte, header Header,
+	options Options) (*Image, error) {
+
+	if headerError := isHeaderValid(header); headerError != nil {
+		return nil, headerError
+	}
+
+	// Well formed raw payloads are decoded in parallel. The result is exactly
+	// the same as the one of the sequential decoder, just faster.
+	limits := options.Limits.resolved()
+
+	if err := limits.checkWidth(header.LineWidth); err != nil {
+		return nil, err
+	}
+
+	if err := limits.checkStride(header.Stride); err != nil {
+		return nil, err
+	}
+
+	if format, _ := parseFormat(header.Format); canDecodeParallel(data, header,
+		format) {
+
+		rows := parallelRows(data, header, format)
+
+		if err := limits.checkPixels(rows * (int)(header.LineWidth)); err != nil {
+			return nil, err
+		}
+
+		image := new(Image)
+		image.header = header
+		image.data = decodeParallel(data, rows, header, format, options)
+		return image, nil
+	}
+
+	return parseSequential(data, header, options)
+}
+
+func parseSequential(data []byte, header Header,
+	options Options) (*Image, error) {
+
+	decoder, headerError := NewDecoderWithOptions(bytes.NewReader(data), header,
+		options)
+
+	if headerError != nil {
+		return nil, headerError
+	}
+
+	limits := options.Limits.resolved()
+
+	image := new(Image)
+	image.header = header
+
+	for {
+		row, err := decoder.NextRow()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		image.data = append(image.data, row...)
+
+		if err := limits.checkPixels(len(image.data)); err != nil {
+			return nil, decoder.locate(err)
+		}
+	}
+
+	if header.BottomUp {
+		image.flipRows()
+	}
+
+	return image, nil
+}
+
+func alphaBlend(colour byte, alpha byte) byte {
+	return (byte)(((int)(colour)*(int)(alpha) + 127) / 255)
+}
+
+func alphaBlend16(colour uint16, alpha uint16) uint16 {
+	return (uint16)(((uint32)(colour)*(uint32)(alpha) + 32767) / 65535)
+}
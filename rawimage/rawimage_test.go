@@ -0,0 +1,89 @@
+package rawimage
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func assertColor(pixel *Pixel, rgb ...byte) error {
+
+	if pixel == nil {
+		return errors.New("Pixel was nil")
+	}
+
+	if pixel.Red != rgb[0] || pixel.Green != rgb[1] || pixel.Blue != rgb[2] {
+		return fmt.Errorf("Wrong colour: expected %v, got %s", rgb[:3], pixel)
+	}
+
+	return nil
+}
+
+func TestBlendModesAgreeOnEdges(t *testing.T) {
+	for colour := 0; colour < 256; colour++ {
+		for _, alpha := range []byte{0, 255} {
+			rounded := RoundedBlend.blend(byte(colour), alpha)
+			legacy := LegacyBlend.blend(byte(colour), alpha)
+
+			if rounded != legacy {
+				t.Errorf("Colour %d with alpha %d: rounded %d but legacy %d", colour,
+					alpha, rounded, legacy)
+			}
+		}
+	}
+}
+
+func TestLegacyBlendIsNeverAboveRounded(t *testing.T) {
+	differences := 0
+
+	for alpha := 0; alpha < 256; alpha++ {
+		for colour := 0; colour < 256; colour++ {
+			rounded := RoundedBlend.blend(byte(colour), byte(alpha))
+			legacy := LegacyBlend.blend(byte(colour), byte(alpha))
+
+			if legacy > rounded || rounded-legacy > 1 {
+				t.Fatalf("Colour %d with alpha %d: rounded %d but legacy %d", colour,
+					alpha, rounded, legacy)
+			}
+
+			if legacy != rounded {
+				differences++
+			}
+		}
+	}
+
+	if differences == 0 {
+		t.Error("The legacy blending was exactly the same as the rounded one")
+	}
+}
+
+func TestParseWithBlendModes(t *testing.T) {
+	data := []byte{
+		22, 12, 244, 127,
+	}
+
+	header := newHeader("RGBA", 1, "None")
+
+	rounded, _ := ParseImageWithOptions(data, header, Options{Blend: RoundedBlend})
+
+	if err := assertColor(&rounded.data[0], 11, 6, 122); err != nil {
+		t.Error(err)
+	}
+
+	legacy, _ := ParseImageWithOptions(data, header, Options{Blend: LegacyBlend})
+
+	if err := assertColor(&legacy.data[0], 10, 5, 121); err != nil {
+		t.Error(err)
+	}
+
+	legacy16, _ := ParseImageWithOptions([]byte{0, 3, 0, 0, 0, 0, 0x80, 0},
+		newHeader("RGBA16", 1, "None"), Options{Blend: LegacyBlend})
+
+	if r, _, _, _ := legacy16.data[0].RGBA64(); r != 1 {
+		t.Errorf("Expected legacy 16-bit red to be truncated to 1 but got %d", r)
+	}
+}
+
+func newHeader(format string, width uint, encoding string) Header {
+	return Header{Format: format, LineWidth: width, Encoding: encoding}
+}
@@ -0,0 +1,128 @@
+package rawimage
+
+import (
+	"io"
+	"sync"
+)
+
+// EncodingDecoder turns an encoded payload into a stream of raw pixel bytes in
+// the header format. pixelSize is the number of bytes a single pixel takes and
+// header is the already validated header of the payload, for encodings which
+// need more than the pixel size.
+//
+// The returned reader should end with io.EOF on a pixel boundary and report
+// broken payloads with an error of its own. A trailing partial pixel is
+// reported by the caller.
+type EncodingDecoder func(reader io.Reader, pixelSize int, header Header) io.Reader
+
+// EncodingEncoder is the reverse of EncodingDecoder. It gets raw pixel bytes
+// and returns them encoded.
+type EncodingEncoder func(raw []byte, pixelSize int, header Header) []byte
+
+var (
+	encodingsLock sync.RWMutex
+	decoders      = make(map[string]EncodingDecoder)
+	encoders      = make(map[string]EncodingEncoder)
+
+	// The encodings of the package itself. Other code relies on how they
+	// work, ParseImage decodes "None" payloads without its decoder for one,
+	// so they cannot be replaced.
+	builtinEncodings = make(map[string]bool)
+)
+
+func init() {
+	registerBuiltin("None", func(reader io.Reader, _ int, _ Header) io.Reader {
+		return reader
+	}, func(raw []byte, _ int, _ Header) []byte {
+		return raw
+	})
+
+	registerBuiltin("RLE", pixelDecoder(newRLEReader), pixelEncoder(encodeRLE))
+}
+
+// RegisterEncoding makes an encoding known to ParseImage and Decoder. The name
+// is what Header.Encoding has to be set to in order to use it. Registering a
+// name for a second time replaces the previous decoder. The built-in encodings
+// cannot be replaced, and neither an empty name nor a nil decoder can be
+// registered. All of these return an error.
+func RegisterEncoding(name string, decoder EncodingDecoder) error {
+	encodingsLock.Lock()
+	defer encodingsLock.Unlock()
+
+	if err := checkRegistration(name, decoder == nil); err != nil {
+		return err
+	}
+
+	decoders[name] = decoder
+	return nil
+}
+
+// RegisterEncoder makes Encode able to produce payloads in an encoding. It is
+// optional, encodings without an encoder can still be parsed. As with
+// RegisterEncoding, the built-in encoders cannot be replaced and the name and
+// the encoder are required.
+func RegisterEncoder(name string, encoder EncodingEncoder) error {
+	encodingsLock.Lock()
+	defer encodingsLock.Unlock()
+
+	if err := checkRegistration(name, encoder == nil); err != nil {
+		return err
+	}
+
+	encoders[name] = encoder
+	return nil
+}
+
+func registerBuiltin(name string, decoder EncodingDecoder,
+	encoder EncodingEncoder) {
+
+	encodingsLock.Lock()
+	defer encodingsLock.Unlock()
+
+	decoders[name] = decoder
+	encoders[name] = encoder
+	builtinEncodings[name] = true
+}
+
+// Most encodings need nothing but the pixel size
+func pixelDecoder(decode func(io.Reader, int) io.Reader) EncodingDecoder {
+	return func(reader io.Reader, pixelSize int, _ Header) io.Reader {
+		return decode(reader, pixelSize)
+	}
+}
+
+func pixelEncoder(encode func([]byte, int) []byte) EncodingEncoder {
+	return func(raw []byte, pixelSize int, _ Header) []byte {
+		return encode(raw, pixelSize)
+	}
+}
+
+func checkRegistration(name string, missing bool) error {
+	switch {
+	case name == "":
+		return newImageError(BadEncoding, "Encoding name cannot be empty")
+	case missing:
+		return newImageError(BadEncoding,
+			"Encoding "+name+" cannot be registered without a function")
+	case builtinEncodings[name]:
+		return newImageError(BadEncoding,
+			"The built-in encoding "+name+" cannot be replaced")
+	}
+	return nil
+}
+
+func lookupEncoding(name string) (EncodingDecoder, bool) {
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
+
+	decoder, ok := decoders[name]
+	return decoder, ok
+}
+
+func lookupEncoder(name string) (EncodingEncoder, bool) {
+	encodingsLock.RLock()
+	defer encodingsLock.RUnlock()
+
+	encoder, ok := encoders[name]
+	return encoder, ok
+}
@@ -0,0 +1,168 @@
+package rawimage
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+type invertingReader struct {
+	reader io.Reader
+}
+
+func (inverting *invertingReader) Read(buffer []byte) (int, error) {
+	read, err := inverting.reader.Read(buffer)
+	for index := range buffer[:read] {
+		buffer[index] ^= 0xff
+	}
+	return read, err
+}
+
+func invertBytes(raw []byte, _ int, _ Header) []byte {
+	out := make([]byte, len(raw))
+	for index, value := range raw {
+		out[index] = value ^ 0xff
+	}
+	return out
+}
+
+func TestRegisteredEncoding(t *testing.T) {
+	RegisterEncoding("TestInverted", func(reader io.Reader, _ int, _ Header) io.Reader {
+		return &invertingReader{reader}
+	})
+
+	header := newHeader("RGB", 2, "TestInverted")
+
+	picture, parseError := ParseImage([]byte{255, 254, 253, 0, 1, 2}, header)
+
+	if parseError != nil {
+		t.Fatalf("Parsing the image returned error: %s", parseError)
+	}
+
+	if err := assertColor(&picture.data[1], 255, 254, 253); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := Encode(picture, header); err == nil {
+		t.Error("No error when encoding without a registered encoder")
+	}
+
+	RegisterEncoder("TestInverted", invertBytes)
+
+	encoded, err := Encode(picture, header)
+
+	if err != nil {
+		t.Fatalf("Encoding returned error: %s", err)
+	}
+
+	if expected := []byte{255, 254, 253, 0, 1, 2}; !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+}
+
+func TestRegisteredEncodingErrors(t *testing.T) {
+	RegisterEncoding("TestAlwaysBroken", func(io.Reader, int, Header) io.Reader {
+		return brokenReader{}
+	})
+
+	_, parseError := ParseImage([]byte{1, 2, 3}, newHeader("RGB", 1, "TestAlwaysBroken"))
+
+	var imageError *ImageError
+
+	if !errors.As(parseError, &imageError) || imageError.Message != "Broken on purpose" {
+		t.Errorf("Expected the decoder error but got %v", parseError)
+	}
+
+	_, err := ParseImage(nil, newHeader("RGB", 1, "TestNotRegistered"))
+
+	if !errors.Is(err, ErrBadEncoding) {
+		t.Error("No error for an encoding which is not registered")
+	}
+}
+
+func TestRegisteredEncodingGetsHeader(t *testing.T) {
+	var received Header
+
+	RegisterEncoding("TestHeader", func(reader io.Reader, _ int,
+		header Header) io.Reader {
+
+		received = header
+		return reader
+	})
+
+	header := newHeader("RGB", 1, "TestHeader")
+	header.Palette = []Pixel{rgba(1, 2, 3, 255)}
+
+	if _, err := ParseImage([]byte{1, 2, 3}, header); err != nil {
+		t.Fatalf("Parsing the image returned error: %s", err)
+	}
+
+	if !reflect.DeepEqual(received, header) {
+		t.Errorf("Expected the decoder to get %v but it got %v", header, received)
+	}
+}
+
+func TestRegisteringNothing(t *testing.T) {
+	decoder := func(reader io.Reader, _ int, _ Header) io.Reader { return reader }
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"TestNil", RegisterEncoding("TestNil", nil)},
+		{"TestNil encoder", RegisterEncoder("TestNil", nil)},
+		{"empty", RegisterEncoding("", decoder)},
+		{"empty encoder", RegisterEncoder("", invertBytes)},
+	}
+
+	for _, test := range cases {
+		var imageError *ImageError
+
+		if !errors.As(test.err, &imageError) || imageError.Code != BadEncoding {
+			t.Errorf("Expected a bad encoding error for %s but got %v", test.name,
+				test.err)
+		}
+	}
+
+	// Nothing was registered so parsing fails without a crash
+	if _, err := ParseImage([]byte{1, 2, 3}, newHeader("RGB", 1,
+		"TestNil")); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("Expected an unknown encoding but got %v", err)
+	}
+
+	if _, err := Encode(numberedImage(t), newHeader("Y", 3,
+		"TestNil")); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("Expected an unknown encoding but got %v", err)
+	}
+}
+
+type brokenReader struct{}
+
+func (broken brokenReader) Read([]byte) (int, error) {
+	return 0, newImageError(BadEncoding, "Broken on purpose")
+}
+
+func TestBuiltinEncodingsCannotBeReplaced(t *testing.T) {
+	for _, name := range []string{"None", "RLE", "PackBits", "Indexed", "IndexedRLE",
+		"Predict", "PredictRLE"} {
+		err := RegisterEncoding(name, func(reader io.Reader, _ int, _ Header) io.Reader {
+			return reader
+		})
+
+		if !errors.Is(err, ErrBadEncoding) {
+			t.Errorf("Expected an error when registering %s but got %v", name, err)
+		}
+
+		if err := RegisterEncoder(name, invertBytes); !errors.Is(err, ErrBadEncoding) {
+			t.Errorf("Expected an error when registering an encoder for %s but got %v",
+				name, err)
+		}
+	}
+
+	encoded, _ := Encode(numberedImage(t), newHeader("Y", 3, "None"))
+
+	if expected := []byte{1, 2, 3, 4, 5, 6}; !reflect.DeepEqual(encoded, expected) {
+		t.Errorf("Expected %v but got %v", expected, encoded)
+	}
+}
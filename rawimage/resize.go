@@ -0,0 +1,202 @@
+package rawimage
+
+import (
+	"math"
+)
+
+// ResampleFilter chooses how Resize calculates the new pixels
+type ResampleFilter int
+
+const (
+	// Every new pixel is a copy of the closest old one. Fast and blocky.
+	NearestNeighbour ResampleFilter = iota
+
+	// Linear interpolation between the neighbouring pixels
+	Bilinear
+
+	// Windowed sinc with three lobes. The sharpest of the three and the
+	// slowest.
+	Lanczos
+)
+
+// How far from its centre the filter kernel is not zero
+func (filter ResampleFilter) support() float64 {
+	switch filter {
+	case Bilinear:
+		return 1
+	case Lanczos:
+		return 3
+	}
+	return 0.5
+}
+
+func (filter ResampleFilter) kernel(x float64) float64 {
+	x = math.Abs(x)
+
+	switch filter {
+	case Bilinear:
+		if x < 1 {
+			return 1 - x
+		}
+		return 0
+	case Lanczos:
+		if x == 0 {
+			return 1
+		}
+		if x >= 3 {
+			return 0
+		}
+		return 3 * math.Sin(math.Pi*x) * math.Sin(math.Pi*x/3) /
+			(math.Pi * math.Pi * x * x)
+	}
+
+	return 0
+}
+
+// Resize returns a scaled copy of img which is width x height pixels big.
+//
+// The filters work on the premultiplied colour. That way transparent pixels,
+// which are black once premultiplied, do not add any colour to their
+// neighbours and edges around them do not get a dark halo.
+func Resize(img *Image, width, height int, filter ResampleFilter) (*Image, error) {
+	if width <= 0 || height <= 0 {
+		return nil, newImageError(BadDimensions, "Resizing needs positive dimensions")
+	}
+
+	if img.width() == 0 || img.height() == 0 {
+		return nil, newImageError(BadDimensions, "Cannot resize an empty image")
+	}
+
+	if filter == NearestNeighbour {
+		return resizeNearest(img, width, height), nil
+	}
+
+	sourceWidth, sourceHeight := img.width(), img.height()
+
+	columns := resampleWeights(sourceWidth, width, filter)
+	rows := resampleWeights(sourceHeight, height, filter)
+
+	// Horizontal pass, every source row gets the new width
+	horizontal := make([][4]float64, sourceHeight*width)
+
+	for y := 0; y < sourceHeight; y++ {
+		row := img.row(y)
+		for x, taps := range columns {
+			var sum [4]float64
+			for _, tap := range taps {
+				addWeighted(&sum, &row[tap.index], tap.weight)
+			}
+			horizontal[y*width+x] = sum
+		}
+	}
+
+	// Vertical pass over the result of the horizontal one
+	result := img.withWidth(width)
+	result.data = make([]Pixel, width*height)
+
+	for y, taps := range rows {
+		for x := 0; x < width; x++ {
+			var sum [4]float64
+			for _, tap := range taps {
+				channels := horizontal[tap.index*width+x]
+				for channel := range sum {
+					sum[channel] += channels[channel] * tap.weight
+				}
+			}
+			result.data[y*width+x] = pixelFromSums(sum)
+		}
+	}
+
+	return result, nil
+}
+
+func resizeNearest(img *Image, width, height int) *Image {
+	scaleX := (float64)(img.width()) / (float64)(width)
+	scaleY := (float64)(img.height()) / (float64)(height)
+
+	return img.transformed(width, height, func(x, y int) (int, int) {
+		sourceX := (int)(((float64)(x) + 0.5) * scaleX)
+		sourceY := (int)(((float64)(y) + 0.5) * scaleY)
+		return min(sourceX, img.width()-1), min(sourceY, img.height()-1)
+	})
+}
+
+type resampleTap struct {
+	index  int
+	weight float64
+}
+
+// For every one of the destination pixels on an axis, which source pixels
+// make it and how much each of them weighs.
+func resampleWeights(source, destination int,
+	filter ResampleFilter) [][]resampleTap {
+
+	scale := (float64)(source) / (float64)(destination)
+
+	// When shrinking the filter has to be stretched so that every source pixel
+	// is taken into account
+	filterScale := math.Max(scale, 1)
+	support := filter.support() * filterScale
+
+	weights := make([][]resampleTap, destination)
+
+	for index := range weights {
+		centre := ((float64)(index)+0.5)*scale - 0.5
+		first := (int)(math.Ceil(centre - support))
+		last := (int)(math.Floor(centre + support))
+
+		var taps []resampleTap
+		total := 0.0
+
+		for tap := first; tap <= last; tap++ {
+			weight := filter.kernel(((float64)(tap) - centre) / filterScale)
+			if weight == 0 {
+				continue
+			}
+
+			// Pixels outside of the image are the same as the closest edge
+			clamped := max(0, min(tap, source-1))
+			taps = append(taps, resampleTap{clamped, weight})
+			total += weight
+		}
+
+		for tap := range taps {
+			taps[tap].weight /= total
+		}
+
+		weights[index] = taps
+	}
+
+	return weights
+}
+
+func addWeighted(sum *[4]float64, pixel *Pixel, weight float64) {
+	red, green, blue, alpha := pixel.RGBA64()
+	sum[0] += (float64)(red) * weight
+	sum[1] += (float64)(green) * weight
+	sum[2] += (float64)(blue) * weight
+	sum[3] += (float64)(alpha) * weight
+}
+
+// Lanczos overshoots so the sums may be out of range, or even a colour which
+// is brighter than its alpha allows for a premultiplied pixel.
+func pixelFromSums(sum [4]float64) Pixel {
+	alpha := clampChannel(sum[3], 0xffff)
+
+	return newPixel16(
+		clampChannel(sum[0], alpha),
+		clampChannel(sum[1], alpha),
+		clampChannel(sum[2], alpha),
+		alpha,
+	)
+}
+
+func clampChannel(value float64, limit uint16) uint16 {
+	if value <= 0 {
+		return 0
+	}
+	if value >= (float64)(limit) {
+		return limit
+	}
+	return (uint16)(math.Round(value))
+}
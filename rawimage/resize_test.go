@@ -0,0 +1,115 @@
+package rawimage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResizeNearestNeighbour(t *testing.T) {
+	picture := numberedImage(t)
+
+	larger, err := Resize(picture, 6, 4, NearestNeighbour)
+
+	if err != nil {
+		t.Fatalf("Resizing returned error: %s", err)
+	}
+
+	assertNumbers(t, "NearestNeighbour", larger, [][]byte{
+		{1, 1, 2, 2, 3, 3},
+		{1, 1, 2, 2, 3, 3},
+		{4, 4, 5, 5, 6, 6},
+		{4, 4, 5, 5, 6, 6},
+	})
+
+	smaller, _ := Resize(picture, 1, 1, NearestNeighbour)
+
+	assertNumbers(t, "NearestNeighbour", smaller, [][]byte{{5}})
+}
+
+func TestResizeKeepsFlatColour(t *testing.T) {
+	data := make([]byte, 0, 5*4*4)
+	for index := 0; index < 5*4; index++ {
+		data = append(data, 200, 100, 50, 128)
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 5, "None"))
+
+	for _, filter := range []ResampleFilter{Bilinear, Lanczos} {
+		for _, size := range [][2]int{{2, 3}, {11, 7}} {
+			resized, err := Resize(picture, size[0], size[1], filter)
+
+			if err != nil {
+				t.Fatalf("Resizing returned error: %s", err)
+			}
+
+			if bounds := resized.Bounds(); bounds.Dx() != size[0] ||
+				bounds.Dy() != size[1] {
+				t.Errorf("Filter %d: expected size %v but got %v", filter, size,
+					bounds)
+			}
+
+			for index, pixel := range resized.data {
+				if pixel != picture.data[0] {
+					t.Errorf("Filter %d, size %v, pixel %d: expected %s but got %s",
+						filter, size, index, picture.data[0], pixel)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestResizeHasNoDarkHalo(t *testing.T) {
+	data := []byte{
+		255, 0, 0, 255, 0, 0, 0, 0,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+
+	for _, filter := range []ResampleFilter{Bilinear, Lanczos} {
+		resized, _ := Resize(picture, 1, 1, filter)
+
+		red, green, blue, alpha := resized.data[0].Straight()
+
+		if red != 255 || green != 0 || blue != 0 || alpha < 127 || alpha > 128 {
+			t.Errorf("Filter %d: expected half transparent red but got %d %d %d %d",
+				filter, red, green, blue, alpha)
+		}
+	}
+}
+
+func TestResizeLanczosStaysPremultiplied(t *testing.T) {
+	data := []byte{
+		0, 0, 0, 255, 0, 0, 0, 255, 255, 255, 255, 10, 0, 0, 0, 255,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 4, "None"))
+
+	resized, _ := Resize(picture, 9, 1, Lanczos)
+
+	for index, pixel := range resized.data {
+		r, g, b, a := pixel.RGBA64()
+		if r > a || g > a || b > a {
+			t.Errorf("Pixel %d is brighter than its alpha: %d %d %d %d", index,
+				r, g, b, a)
+		}
+	}
+}
+
+func TestResizeWithBadDimensions(t *testing.T) {
+	picture := numberedImage(t)
+
+	for _, size := range [][2]int{{0, 1}, {1, 0}, {-2, 2}} {
+		_, err := Resize(picture, size[0], size[1], Bilinear)
+
+		if !errors.Is(err, ErrBadDimensions) {
+			t.Errorf("Size %v: expected bad dimensions error but got %v", size, err)
+		}
+	}
+
+	empty, _ := ParseImage(nil, newHeader("RGB", 2, "None"))
+
+	if _, err := Resize(empty, 1, 1, Lanczos); !errors.Is(err, ErrBadDimensions) {
+		t.Errorf("Expected bad dimensions error for an empty image but got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package rawimage
+
+import (
+	"math"
+)
+
+// ChannelStats describes the values of a single channel over a whole image
+type ChannelStats struct {
+	// How many pixels have each of the values
+	Histogram [256]int
+
+	Min, Max     byte
+	Mean, StdDev float64
+}
+
+// ImageStats is what Stats finds out about an image. The colour channels are
+// the premultiplied ones, the same which Pixel holds. Deep pixels are counted
+// by their 8-bit value.
+type ImageStats struct {
+	Red, Green, Blue, Alpha ChannelStats
+
+	Pixels int
+
+	// Pixels with alpha of exactly 0 and 255
+	Transparent, Opaque int
+
+	// Number of different premultiplied colours, alpha included
+	Colours int
+}
+
+// Stats goes over every pixel of img once and gathers its statistics. An empty
+// image gives zero for everything.
+func Stats(img *Image) *ImageStats {
+	stats := new(ImageStats)
+	channels := []*ChannelStats{&stats.Red, &stats.Green, &stats.Blue, &stats.Alpha}
+	colours := make(map[[4]byte]struct{})
+
+	for y := 0; y < img.height(); y++ {
+		row := img.row(y)
+		for x := range row {
+			red, green, blue, alpha := row[x].Premultiplied()
+			values := [4]byte{red, green, blue, alpha}
+
+			for index, channel := range channels {
+				channel.Histogram[values[index]]++
+			}
+
+			switch alpha {
+			case 0:
+				stats.Transparent++
+			case 255:
+				stats.Opaque++
+			}
+
+			colours[values] = struct{}{}
+			stats.Pixels++
+		}
+	}
+
+	stats.Colours = len(colours)
+
+	for _, channel := range channels {
+		channel.summarise(stats.Pixels)
+	}
+
+	return stats
+}
+
+// Fills everything but the histogram from the histogram
+func (channel *ChannelStats) summarise(pixels int) {
+	if pixels == 0 {
+		return
+	}
+
+	channel.Min, channel.Max = 255, 0
+	sum := 0.0
+
+	for value, count := range channel.Histogram {
+		if count == 0 {
+			continue
+		}
+
+		channel.Min = min(channel.Min, (byte)(value))
+		channel.Max = max(channel.Max, (byte)(value))
+		sum += (float64)(value * count)
+	}
+
+	channel.Mean = sum / (float64)(pixels)
+	variance := 0.0
+
+	for value, count := range channel.Histogram {
+		deviation := (float64)(value) - channel.Mean
+		variance += deviation * deviation * (float64)(count)
+	}
+
+	channel.StdDev = math.Sqrt(variance / (float64)(pixels))
+}
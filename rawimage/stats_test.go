@@ -0,0 +1,70 @@
+package rawimage
+
+import (
+	"image"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	data := []byte{
+		255, 0, 0, 255,
+		255, 0, 0, 255,
+		0, 0, 0, 0,
+		0, 100, 0, 128,
+	}
+
+	picture, _ := ParseImage(data, newHeader("RGBA", 2, "None"))
+	stats := Stats(picture)
+
+	if stats.Pixels != 4 || stats.Transparent != 1 || stats.Opaque != 2 {
+		t.Errorf("Wrong pixel counts: %d pixels, %d transparent, %d opaque",
+			stats.Pixels, stats.Transparent, stats.Opaque)
+	}
+
+	if stats.Colours != 3 {
+		t.Errorf("Expected 3 colours but found %d", stats.Colours)
+	}
+
+	red := stats.Red
+
+	if red.Histogram[255] != 2 || red.Histogram[0] != 2 {
+		t.Errorf("Wrong red histogram: 255 is %d and 0 is %d",
+			red.Histogram[255], red.Histogram[0])
+	}
+
+	if red.Min != 0 || red.Max != 255 || red.Mean != 127.5 || red.StdDev != 127.5 {
+		t.Errorf("Wrong red stats: %+v", red)
+	}
+
+	// Premultiplied 100 * 128 / 255
+	if stats.Green.Max != 50 || stats.Green.Mean != 12.5 {
+		t.Errorf("Wrong green stats: max %d, mean %f",
+			stats.Green.Max, stats.Green.Mean)
+	}
+
+	if stats.Blue.Min != 0 || stats.Blue.Max != 0 || stats.Blue.StdDev != 0 {
+		t.Errorf("Wrong blue stats: %+v", stats.Blue)
+	}
+}
+
+func TestStatsOfSubImage(t *testing.T) {
+	picture := numberedImage(t)
+	part, _ := picture.SubImage(image.Rect(1, 0, 3, 2))
+	stats := Stats(part)
+
+	if stats.Pixels != 4 || stats.Colours != 4 {
+		t.Errorf("Wrong counts: %d pixels and %d colours", stats.Pixels, stats.Colours)
+	}
+
+	if stats.Red.Min != 2 || stats.Red.Max != 6 || stats.Red.Mean != 4 {
+		t.Errorf("Wrong red stats: %+v", stats.Red)
+	}
+}
+
+func TestStatsOfEmptyImage(t *testing.T) {
+	picture, _ := ParseImage([]byte{}, newHeader("RGB", 1, "None"))
+
+	if stats := Stats(picture); *stats != (ImageStats{}) {
+		t.Errorf("Expected zero stats but got %+v", stats)
+	}
+}
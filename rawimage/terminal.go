@@ -0,0 +1,162 @@
+package rawimage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// TerminalMode chooses the kind of output RenderTerminal writes
+type TerminalMode int
+
+const (
+	// 24-bit ANSI colour with two pixels in every character, the top one in
+	// the foreground of an upper half block and the bottom one in the
+	// background
+	TrueColour TerminalMode = iota
+
+	// The same half blocks with the closest colours of the xterm 256 colour
+	// palette, for terminals without true colour
+	Colour256
+
+	// No escape codes at all. Every character stands for two pixels and gets
+	// brighter the brighter they are, which suits dark terminals.
+	ASCII
+)
+
+// Transparent parts of the image show a checkerboard of these two grays with
+// squares of checkerSize pixels, like image editors do
+const (
+	checkerSize  = 4
+	checkerLight = 204
+	checkerDark  = 153
+)
+
+const (
+	upperHalfBlock = "▀"
+	resetColour    = "\x1b[0m"
+	asciiRamp      = " .:-=+*#%@"
+)
+
+// RenderTerminal writes img to writer for viewing in a terminal. Every line
+// of output covers two rows of pixels and ends with a newline.
+func RenderTerminal(writer io.Writer, img *Image, mode TerminalMode) error {
+	out := bufio.NewWriter(writer)
+
+	for y := 0; y < img.height(); y += 2 {
+		var lastForeground, lastBackground string
+
+		for x := 0; x < img.width(); x++ {
+			top := img.onCheckerboard(x, y)
+			bottom, hasBottom := Pixel{}, y+1 < img.height()
+			if hasBottom {
+				bottom = img.onCheckerboard(x, y+1)
+			}
+
+			if mode == ASCII {
+				brightness := (int)(top.luminance())
+				if hasBottom {
+					brightness = (brightness + (int)(bottom.luminance())) / 2
+				}
+				out.WriteByte(asciiRamp[brightness*len(asciiRamp)/256])
+				continue
+			}
+
+			// An odd last row leaves the terminal background under it
+			foreground := mode.colour(&top, 38)
+			background := "\x1b[49m"
+			if hasBottom {
+				background = mode.colour(&bottom, 48)
+			}
+
+			// Neighbours of the same colour share the escape codes
+			if foreground != lastForeground {
+				out.WriteString(foreground)
+				lastForeground = foreground
+			}
+			if background != lastBackground {
+				out.WriteString(background)
+				lastBackground = background
+			}
+
+			out.WriteString(upperHalfBlock)
+		}
+
+		if mode != ASCII && img.width() > 0 {
+			out.WriteString(resetColour)
+		}
+		out.WriteByte('\n')
+	}
+
+	return out.Flush()
+}
+
+// The pixel at x, y composited over the checkerboard, which makes it opaque
+func (img *Image) onCheckerboard(x, y int) Pixel {
+	checker := byte(checkerLight)
+	if (x/checkerSize+y/checkerSize)%2 == 1 {
+		checker = checkerDark
+	}
+
+	pixel := img.data[img.pixelIndex(x, y)]
+
+	return pixel.Composite(Pixel{Red: checker, Green: checker, Blue: checker,
+		Alpha: 255}, Over)
+}
+
+func (pixel *Pixel) luminance() byte {
+	return (byte)(luminance((uint32)(pixel.Red), (uint32)(pixel.Green),
+		(uint32)(pixel.Blue)))
+}
+
+// The escape code setting an opaque pixel as the foreground with 38 or the
+// background with 48
+func (mode TerminalMode) colour(pixel *Pixel, target int) string {
+	if mode == Colour256 {
+		return fmt.Sprintf("\x1b[%d;5;%dm", target, xterm256(pixel))
+	}
+
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", target, pixel.Red, pixel.Green,
+		pixel.Blue)
+}
+
+// The levels of every channel of the 6x6x6 colour cube of xterm
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// The closest colour out of the cube, which starts at 16, and the gray ramp
+// from 232 to 255. The 16 system colours are left out as every terminal has
+// them in a different shade.
+func xterm256(pixel *Pixel) int {
+	channels := [3]int{(int)(pixel.Red), (int)(pixel.Green), (int)(pixel.Blue)}
+
+	var cube [3]int
+	for channel, value := range channels {
+		for level := range cubeLevels {
+			if abs(cubeLevels[level]-value) < abs(cubeLevels[cube[channel]]-value) {
+				cube[channel] = level
+			}
+		}
+	}
+
+	cubeColour := [3]int{cubeLevels[cube[0]], cubeLevels[cube[1]],
+		cubeLevels[cube[2]]}
+
+	average := (channels[0] + channels[1] + channels[2]) / 3
+	grayIndex := max(0, min(23, (average-8+5)/10))
+	grayLevel := 8 + grayIndex*10
+	gray := [3]int{grayLevel, grayLevel, grayLevel}
+
+	if distance(channels, gray) < distance(channels, cubeColour) {
+		return 232 + grayIndex
+	}
+
+	return 16 + 36*cube[0] + 6*cube[1] + cube[2]
+}
+
+func distance(a, b [3]int) int {
+	sum := 0
+	for channel := range a {
+		sum += (a[channel] - b[channel]) * (a[channel] - b[channel])
+	}
+	return sum
+}
@@ -0,0 +1,90 @@
+package rawimage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, picture *Image, mode TerminalMode) string {
+	var out bytes.Buffer
+	if err := RenderTerminal(&out, picture, mode); err != nil {
+		t.Fatalf("Rendering failed: %s", err)
+	}
+	return out.String()
+}
+
+func TestRenderTrueColour(t *testing.T) {
+	data := []byte{
+		255, 0, 0, 255, 255, 0, 0, 255, 0, 0, 255, 255,
+		0, 255, 0, 255, 0, 255, 0, 255, 0, 0, 255, 255,
+		9, 9, 9, 255, 9, 9, 9, 255, 9, 9, 9, 255,
+	}
+
+	picture := mustParse(t, data, newHeader("RGBA", 3, "None"))
+
+	expected := "\x1b[38;2;255;0;0m\x1b[48;2;0;255;0m▀▀" +
+		"\x1b[38;2;0;0;255m\x1b[48;2;0;0;255m▀\x1b[0m\n" +
+		"\x1b[38;2;9;9;9m\x1b[49m▀▀▀\x1b[0m\n"
+
+	if found := render(t, picture, TrueColour); found != expected {
+		t.Errorf("Expected %q but found %q", expected, found)
+	}
+}
+
+func TestRenderCheckerboard(t *testing.T) {
+	data := make([]byte, 8*2*4)
+	// Half transparent white in the first pixel
+	copy(data, []byte{255, 255, 255, 128})
+
+	picture := mustParse(t, data, newHeader("RGBA", 8, "None"))
+	found := render(t, picture, TrueColour)
+
+	if !strings.HasPrefix(found, "\x1b[38;2;230;230;230m\x1b[48;2;204;204;204m▀") {
+		t.Errorf("Expected white blended with the light square in %q", found)
+	}
+
+	if !strings.Contains(found, "\x1b[38;2;153;153;153m\x1b[48;2;153;153;153m▀") {
+		t.Errorf("Expected a dark square in %q", found)
+	}
+}
+
+func TestRender256Colours(t *testing.T) {
+	for _, example := range []struct {
+		pixel    Pixel
+		expected int
+	}{
+		{rgba(255, 0, 0, 255), 196},
+		{rgba(0, 0, 0, 255), 16},
+		{rgba(255, 255, 255, 255), 231},
+		{rgba(128, 128, 128, 255), 244},
+		{rgba(0, 95, 135, 255), 24},
+	} {
+		if found := xterm256(&example.pixel); found != example.expected {
+			t.Errorf("Expected colour %d for %s but found %d", example.expected,
+				example.pixel, found)
+		}
+	}
+
+	picture := mustParse(t, []byte{255, 0, 0, 0, 0, 255}, newHeader("RGB", 1, "None"))
+	expected := "\x1b[38;5;196m\x1b[48;5;21m▀\x1b[0m\n"
+
+	if found := render(t, picture, Colour256); found != expected {
+		t.Errorf("Expected %q but found %q", expected, found)
+	}
+}
+
+func TestRenderASCII(t *testing.T) {
+	data := []byte{
+		0, 255, 128, 255,
+		0, 255, 0, 255,
+		255, 0, 255, 255,
+	}
+
+	picture := mustParse(t, data, newHeader("Y", 4, "None"))
+	expected := " @:@\n@ @@\n"
+
+	if found := render(t, picture, ASCII); found != expected {
+		t.Errorf("Expected %q but found %q", expected, found)
+	}
+}